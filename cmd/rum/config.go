@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Print the effective configuration",
+	Long: `Load rum.yaml the same way gen does, expand ${VAR} environment references
+(with expand_env: true), fill in defaults for unset options and print the
+result as YAML.
+
+Use it to check exactly which configuration gen and render act on.
+`,
+	Args: cobra.NoArgs,
+	RunE: runConfig,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfig(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if cfg.Templates != nil {
+		cfg.Templates = cfg.Templates.WithDefaults()
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+	_, err = cmd.OutOrStdout().Write(out)
+	return err
+}
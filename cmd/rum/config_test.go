@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/4Sigma/rum/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigCommand(t *testing.T) {
+	t.Setenv("RUM_TEST_PACKAGE", "views")
+
+	path := filepath.Join(t.TempDir(), "rum.yaml")
+	os.WriteFile(path, []byte(`
+expand_env: true
+templates:
+  package: "${RUM_TEST_PACKAGE}"
+  dirs:
+    - "templates/*.tmpl"
+`), 0644)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+	resetConfigFlag(t)
+	rootCmd.SetArgs([]string{"config", "--config", path})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("rum config: %v", err)
+	}
+
+	var printed config.Config
+	if err := yaml.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatalf("output is not valid YAML: %v\n%s", err, out.String())
+	}
+	if printed.Templates == nil || printed.Templates.Package != "views" {
+		t.Fatalf("expected expanded package %q, got:\n%s", "views", out.String())
+	}
+	// The root is resolved against the config file's directory.
+	if printed.Templates.Root != filepath.Dir(path) || printed.Templates.NumericPrefix != config.DefaultNumericPrefix {
+		t.Errorf("expected defaults to be filled in, got:\n%s", out.String())
+	}
+}
+
+// resetConfigFlag restores the global --config flag after the test, since
+// cobra keeps flag values and their changed state between Execute calls.
+func resetConfigFlag(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		flag := rootCmd.PersistentFlags().Lookup("config")
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	})
+}
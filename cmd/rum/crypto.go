@@ -0,0 +1,332 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/4Sigma/rum/crypto/block_cipher"
+	"github.com/4Sigma/rum/crypto/keyring"
+)
+
+var (
+	cryptoKeyring     string
+	cryptoPasswordEnv string
+	encryptMode       string
+	decryptMode       string
+
+	reencryptNewPasswordEnv string
+	reencryptDryRun         bool
+
+	// keyringProvider resolves --keyring references; tests replace it.
+	keyringProvider keyring.Provider = keyring.SystemProvider{}
+)
+
+// Cipher modes accepted by --mode.
+const (
+	modeCBC = "cbc"
+	modeGCM = "gcm"
+)
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt <input> <output>",
+	Short: "Encrypt a file (OpenSSL aes-256-cbc -pbkdf2 compatible by default)",
+	Long: `Encrypt a file with a PBKDF2-derived key. Use "-" for stdin or stdout.
+
+--mode selects the format:
+  cbc  AES-256-CBC, identical to "openssl aes-256-cbc -pbkdf2" (default)
+  gcm  authenticated AES-256-GCM, detects tampering but is rum-specific
+
+The password is read from the OS keyring with --keyring <service>/<user>,
+or otherwise from the environment variable named by --password-env.
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: runEncrypt,
+}
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt <input> <output>",
+	Short: "Decrypt a file produced by rum encrypt or openssl",
+	Long: `Decrypt a file encrypted with "rum encrypt" or "openssl aes-256-cbc -pbkdf2".
+Use "-" for stdin or stdout. The password is resolved like for encrypt.
+
+The format is detected from the file header; --mode forces cbc or gcm.
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDecrypt,
+}
+
+var reencryptCmd = &cobra.Command{
+	Use:   "reencrypt <file>...",
+	Short: "Re-encrypt files in place with a new password",
+	Long: `Decrypt each file with the current password and encrypt it again with the
+new password, read from the environment variable named by --new-password-env.
+Files are replaced only after they were fully re-encrypted.
+
+Only the default cbc format is supported; gcm and hmac files are reported as
+failures. Re-encrypted files keep their permissions.
+
+Every file is processed even if some fail, and a per-file report is printed.
+With --dry-run nothing is written: each file is only checked to decrypt and
+the size of the re-encrypted output is reported.
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runReencrypt,
+}
+
+func init() {
+	encryptCmd.Flags().StringVar(&encryptMode, "mode", modeCBC, "cipher mode: cbc or gcm")
+	decryptCmd.Flags().StringVar(&decryptMode, "mode", "", "cipher mode: cbc or gcm (default: detect from header)")
+	reencryptCmd.Flags().StringVar(&reencryptNewPasswordEnv, "new-password-env", "RUM_NEW_PASSWORD", "environment variable holding the new password")
+	reencryptCmd.Flags().BoolVar(&reencryptDryRun, "dry-run", false, "verify files and report output sizes without writing")
+
+	for _, cmd := range []*cobra.Command{encryptCmd, decryptCmd, reencryptCmd} {
+		cmd.Flags().StringVar(&cryptoKeyring, "keyring", "", "read the password from the OS keyring entry <service>/<user>")
+		cmd.Flags().StringVar(&cryptoPasswordEnv, "password-env", "RUM_PASSWORD", "environment variable holding the password")
+		rootCmd.AddCommand(cmd)
+	}
+}
+
+func runEncrypt(cmd *cobra.Command, args []string) error {
+	switch encryptMode {
+	case modeCBC:
+		return runCipher(cmd, args, block_cipher.EncryptStream)
+	case modeGCM:
+		return runCipher(cmd, args, func(w io.Writer, r io.Reader, password []byte) error {
+			return block_cipher.EncryptStreamGCM(w, r, password, nil)
+		})
+	default:
+		return fmt.Errorf("unknown mode %q: expected %s or %s", encryptMode, modeCBC, modeGCM)
+	}
+}
+
+func runDecrypt(cmd *cobra.Command, args []string) error {
+	switch decryptMode {
+	case "":
+		return runCipher(cmd, args, block_cipher.DecryptStreamAuto)
+	case modeCBC:
+		return runCipher(cmd, args, block_cipher.DecryptStream)
+	case modeGCM:
+		return runCipher(cmd, args, func(w io.Writer, r io.Reader, password []byte) error {
+			return block_cipher.DecryptStreamGCM(w, r, password, nil)
+		})
+	default:
+		return fmt.Errorf("unknown mode %q: expected %s or %s", decryptMode, modeCBC, modeGCM)
+	}
+}
+
+// runCipher resolves the password and streams args[0] through fn into args[1].
+func runCipher(cmd *cobra.Command, args []string, fn func(io.Writer, io.Reader, []byte) error) error {
+	password, err := resolvePassword()
+	if err != nil {
+		return err
+	}
+
+	in, err := openInput(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if args[1] == "-" {
+		return fn(cmd.OutOrStdout(), in, password)
+	}
+
+	out, err := os.Create(args[1])
+	if err != nil {
+		return fmt.Errorf("creating output file: %w", err)
+	}
+	if err := fn(out, in, password); err != nil {
+		out.Close()
+		os.Remove(args[1])
+		return err
+	}
+	return out.Close()
+}
+
+// resolvePassword reads the password from the keyring or the environment.
+func resolvePassword() ([]byte, error) {
+	if cryptoKeyring != "" {
+		service, user, err := keyring.ParseRef(cryptoKeyring)
+		if err != nil {
+			return nil, err
+		}
+		password, err := keyringProvider.Get(service, user)
+		if err != nil {
+			return nil, fmt.Errorf("reading password from keyring %s: %w", cryptoKeyring, err)
+		}
+		return password, nil
+	}
+
+	if password := os.Getenv(cryptoPasswordEnv); password != "" {
+		return []byte(password), nil
+	}
+	return nil, fmt.Errorf("no password: set %s or use --keyring <service>/<user>", cryptoPasswordEnv)
+}
+
+// openInput opens path for reading, with "-" meaning stdin.
+func openInput(cmd *cobra.Command, path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(cmd.InOrStdin()), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening input file: %w", err)
+	}
+	return f, nil
+}
+
+func runReencrypt(cmd *cobra.Command, args []string) error {
+	oldPassword, err := resolvePassword()
+	if err != nil {
+		return err
+	}
+
+	var newPassword []byte
+	if !reencryptDryRun {
+		env := os.Getenv(reencryptNewPasswordEnv)
+		if env == "" {
+			return fmt.Errorf("no new password: set %s", reencryptNewPasswordEnv)
+		}
+		newPassword = []byte(env)
+	}
+
+	results := reencryptFiles(args, oldPassword, newPassword, reencryptDryRun)
+
+	out := cmd.OutOrStdout()
+	failed := 0
+	for _, res := range results {
+		if res.Err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL %s: %v\n", res.Path, res.Err)
+			continue
+		}
+		if reencryptDryRun {
+			fmt.Fprintf(out, "ok   %s (would write %d bytes)\n", res.Path, res.Size)
+		} else {
+			fmt.Fprintf(out, "ok   %s (%d bytes)\n", res.Path, res.Size)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(results))
+	}
+	return nil
+}
+
+// reencryptResult is the outcome of re-encrypting one file.
+type reencryptResult struct {
+	Path string
+	Size int64 // size of the (would-be) re-encrypted file
+	Err  error
+}
+
+// reencryptFiles processes every path, collecting errors instead of stopping
+// at the first failure.
+func reencryptFiles(paths []string, oldPassword, newPassword []byte, dryRun bool) []reencryptResult {
+	results := make([]reencryptResult, 0, len(paths))
+	for _, path := range paths {
+		var size int64
+		var err error
+		if dryRun {
+			size, err = dryRunReencrypt(path, oldPassword)
+		} else {
+			size, err = reencryptFile(path, oldPassword, newPassword)
+		}
+		results = append(results, reencryptResult{Path: path, Size: size, Err: err})
+	}
+	return results
+}
+
+// errNotCBC is returned by reencrypt for files that are not in the CBC
+// format, the only one Reencrypt handles.
+var errNotCBC = errors.New("not in the cbc format: only files written by rum encrypt --mode cbc or openssl can be re-encrypted")
+
+// openCBC opens path for reading, checking that it holds CBC data.
+func openCBC(path string) (*os.File, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := block_cipher.IsEncrypted(in)
+	if err == nil && !ok {
+		err = errNotCBC
+	}
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	return in, nil
+}
+
+// dryRunReencrypt decrypts path without writing and returns the size the
+// re-encrypted file would have.
+func dryRunReencrypt(path string, password []byte) (int64, error) {
+	in, err := openCBC(path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	var plain countingWriter
+	if err := block_cipher.DecryptStream(&plain, in, password); err != nil {
+		return 0, err
+	}
+	return block_cipher.EncryptedSize(int64(plain)), nil
+}
+
+// reencryptFile re-encrypts path into a temporary file in the same directory
+// and renames it over the original once complete, keeping its permissions.
+func reencryptFile(path string, oldPassword, newPassword []byte) (int64, error) {
+	in, err := openCBC(path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	orig, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rum-reencrypt-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp uses 0600, which the rename would otherwise impose.
+	if err := tmp.Chmod(orig.Mode().Perm()); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+
+	if err := block_cipher.Reencrypt(tmp, in, oldPassword, newPassword); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// countingWriter discards data, counting the bytes written.
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/4Sigma/rum/crypto/block_cipher"
+	"github.com/4Sigma/rum/crypto/keyring"
+	"github.com/4Sigma/rum/crypto/phc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// resetFlags restores the flags of cmds, and the globals bound to them, to
+// their defaults after the test, since cobra keeps parsed values between
+// Execute calls.
+func resetFlags(t *testing.T, cmds ...*cobra.Command) {
+	t.Helper()
+	t.Cleanup(func() {
+		for _, cmd := range cmds {
+			cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+				flag.Value.Set(flag.DefValue)
+				flag.Changed = false
+			})
+		}
+	})
+}
+
+func TestEncryptDecryptWithKeyring(t *testing.T) {
+	resetFlags(t, encryptCmd, decryptCmd)
+	provider := keyring.NewMemoryProvider()
+	provider.Set("rum", "backup", []byte("s3cr3t"))
+
+	defer func(p keyring.Provider) { keyringProvider = p }(keyringProvider)
+	keyringProvider = provider
+
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "plain.txt")
+	encPath := filepath.Join(dir, "plain.txt.enc")
+	decPath := filepath.Join(dir, "decrypted.txt")
+	plain := []byte("rum keyring round trip")
+	os.WriteFile(plainPath, plain, 0644)
+
+	for _, args := range [][]string{
+		{"encrypt", "--keyring", "rum/backup", plainPath, encPath},
+		{"decrypt", "--keyring", "rum/backup", encPath, decPath},
+	} {
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("rum %v: %v", args, err)
+		}
+	}
+
+	decrypted, err := os.ReadFile(decPath)
+	if err != nil {
+		t.Fatalf("reading decrypted file: %v", err)
+	}
+	if !bytes.Equal(decrypted, plain) {
+		t.Errorf("decrypted = %q, want %q", decrypted, plain)
+	}
+}
+
+func TestEncryptMissingKeyringEntry(t *testing.T) {
+	resetFlags(t, encryptCmd)
+	defer func(p keyring.Provider) { keyringProvider = p }(keyringProvider)
+	keyringProvider = keyring.NewMemoryProvider()
+
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "plain.txt")
+	os.WriteFile(plainPath, []byte("data"), 0644)
+
+	rootCmd.SetArgs([]string{"encrypt", "--keyring", "rum/missing", plainPath, filepath.Join(dir, "out.enc")})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected error for missing keyring entry")
+	}
+}
+
+func TestResolvePassword(t *testing.T) {
+	provider := keyring.NewMemoryProvider()
+	provider.Set("rum", "backup", []byte("from-keyring"))
+
+	prevProvider, prevKeyring, prevEnv := keyringProvider, cryptoKeyring, cryptoPasswordEnv
+	t.Cleanup(func() {
+		keyringProvider, cryptoKeyring, cryptoPasswordEnv = prevProvider, prevKeyring, prevEnv
+	})
+	keyringProvider = provider
+	cryptoPasswordEnv = "RUM_TEST_PASSWORD"
+
+	tests := []struct {
+		name    string
+		keyring string
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{"keyring wins over env", "rum/backup", "from-env", "from-keyring", false},
+		{"env without keyring", "", "from-env", "from-env", false},
+		{"missing keyring entry does not fall back to env", "rum/missing", "from-env", "", true},
+		{"invalid keyring ref", "rum", "from-env", "", true},
+		{"neither", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cryptoKeyring = tt.keyring
+			t.Setenv("RUM_TEST_PASSWORD", tt.env)
+
+			password, err := resolvePassword()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolvePassword error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(password) != tt.want {
+				t.Errorf("password = %q, want %q", password, tt.want)
+			}
+		})
+	}
+}
+
+func TestReencryptFilesDryRun(t *testing.T) {
+	dir := t.TempDir()
+	plain := bytes.Repeat([]byte("secret "), 100)
+
+	good := filepath.Join(dir, "good.enc")
+	var encrypted bytes.Buffer
+	block_cipher.EncryptStream(&encrypted, bytes.NewReader(plain), []byte("old"))
+	os.WriteFile(good, encrypted.Bytes(), 0644)
+
+	truncated := filepath.Join(dir, "truncated.enc")
+	os.WriteFile(truncated, encrypted.Bytes()[:encrypted.Len()-3], 0644)
+
+	missing := filepath.Join(dir, "missing.enc")
+
+	results := reencryptFiles([]string{good, truncated, missing}, []byte("old"), nil, true)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+
+	if results[0].Err != nil {
+		t.Errorf("good file failed: %v", results[0].Err)
+	}
+	if want := block_cipher.EncryptedSize(int64(len(plain))); results[0].Size != want {
+		t.Errorf("good file size = %d, want %d", results[0].Size, want)
+	}
+	if !errors.Is(results[1].Err, block_cipher.ErrTruncated) {
+		t.Errorf("truncated file error = %v, want ErrTruncated", results[1].Err)
+	}
+	if !errors.Is(results[2].Err, os.ErrNotExist) {
+		t.Errorf("missing file error = %v, want not exist", results[2].Err)
+	}
+
+	// A dry run never touches the files.
+	after, _ := os.ReadFile(good)
+	if !bytes.Equal(after, encrypted.Bytes()) {
+		t.Error("dry run modified the input file")
+	}
+}
+
+func TestReencryptFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.enc")
+
+	var encrypted bytes.Buffer
+	block_cipher.EncryptStream(&encrypted, bytes.NewReader([]byte("rotate")), []byte("old"))
+	os.WriteFile(path, encrypted.Bytes(), 0640)
+	os.Chmod(path, 0640) // regardless of umask
+
+	results := reencryptFiles([]string{path}, []byte("old"), []byte("new"), false)
+	if results[0].Err != nil {
+		t.Fatalf("reencrypt failed: %v", results[0].Err)
+	}
+
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want the original 0640", info.Mode().Perm())
+	}
+
+	f, _ := os.Open(path)
+	defer f.Close()
+	var decrypted bytes.Buffer
+	if err := block_cipher.DecryptStream(&decrypted, f, []byte("new")); err != nil {
+		t.Fatalf("decrypting with new password: %v", err)
+	}
+	if decrypted.String() != "rotate" {
+		t.Errorf("decrypted = %q", decrypted.String())
+	}
+}
+
+func TestReencryptFilesRejectsOtherFormats(t *testing.T) {
+	dir := t.TempDir()
+	plain := []byte("not cbc")
+
+	var gcm, mac bytes.Buffer
+	block_cipher.EncryptStreamGCM(&gcm, bytes.NewReader(plain), []byte("old"), nil)
+	block_cipher.EncryptStreamHMAC(&mac, bytes.NewReader(plain), []byte("old"))
+	files := map[string][]byte{"data.gcm": gcm.Bytes(), "data.hmac": mac.Bytes(), "data.txt": plain}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, content, 0644)
+
+		for _, dryRun := range []bool{true, false} {
+			results := reencryptFiles([]string{path}, []byte("old"), []byte("new"), dryRun)
+			if !errors.Is(results[0].Err, errNotCBC) {
+				t.Errorf("%s (dry run %v): error = %v, want errNotCBC", name, dryRun, results[0].Err)
+			}
+		}
+		if after, _ := os.ReadFile(path); !bytes.Equal(after, content) {
+			t.Errorf("%s was modified", name)
+		}
+	}
+}
+
+func TestEncryptModes(t *testing.T) {
+	resetFlags(t, encryptCmd, decryptCmd)
+	t.Setenv("RUM_PASSWORD", "s3cr3t")
+
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "plain.txt")
+	plain := []byte("rum cipher modes")
+	os.WriteFile(plainPath, plain, 0644)
+
+	_, opensslErr := exec.LookPath("openssl")
+
+	for _, mode := range []string{"cbc", "gcm"} {
+		t.Run(mode, func(t *testing.T) {
+			encPath := filepath.Join(dir, mode+".enc")
+			decPath := filepath.Join(dir, mode+".dec")
+
+			for _, args := range [][]string{
+				{"encrypt", "--mode", mode, plainPath, encPath},
+				{"decrypt", encPath, decPath},
+			} {
+				rootCmd.SetArgs(args)
+				if err := rootCmd.Execute(); err != nil {
+					t.Fatalf("rum %v: %v", args, err)
+				}
+			}
+
+			decrypted, _ := os.ReadFile(decPath)
+			if !bytes.Equal(decrypted, plain) {
+				t.Errorf("auto-detected decryption = %q, want %q", decrypted, plain)
+			}
+
+			if opensslErr != nil {
+				t.Skip("openssl not installed")
+			}
+			out, err := exec.Command("openssl", "aes-256-cbc", "-d", "-pbkdf2", "-k", "s3cr3t", "-in", encPath).Output()
+			switch mode {
+			case "cbc":
+				if err != nil || !bytes.Equal(out, plain) {
+					t.Errorf("openssl could not decrypt cbc output: %v", err)
+				}
+			case "gcm":
+				if err == nil {
+					t.Error("expected openssl to reject gcm output")
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptUnknownMode(t *testing.T) {
+	resetFlags(t, encryptCmd)
+	t.Setenv("RUM_PASSWORD", "s3cr3t")
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "plain.txt")
+	os.WriteFile(plainPath, []byte("data"), 0644)
+
+	rootCmd.SetArgs([]string{"encrypt", "--mode", "ecb", plainPath, filepath.Join(dir, "out.enc")})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestHashCustomParameters(t *testing.T) {
+	resetFlags(t, hashCmd)
+	t.Setenv("RUM_HASH_TEST_PASSWORD", "s3cr3t")
+
+	var out, errOut bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetErr(nil)
+
+	rootCmd.SetArgs([]string{"hash", "--password-env", "RUM_HASH_TEST_PASSWORD",
+		"--memory", "1024", "--iterations", "2", "--parallelism", "1", "--salt-len", "12", "--key-len", "24"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("rum hash: %v", err)
+	}
+
+	hash := strings.TrimSpace(out.String())
+	params, salt, key, err := phc.ParsePHC(hash)
+	if err != nil {
+		t.Fatalf("ParsePHC(%q): %v", hash, err)
+	}
+	if params.Memory != 1024 || params.Iterations != 2 || params.Parallelism != 1 || len(salt) != 12 || len(key) != 24 {
+		t.Errorf("hash %s does not match the requested parameters", hash)
+	}
+	if !strings.Contains(errOut.String(), "warning:") {
+		t.Errorf("expected a weak parameters warning, got %q", errOut.String())
+	}
+
+	rootCmd.SetArgs([]string{"hash", "--password-env", "RUM_HASH_TEST_PASSWORD", "--key-len", "8"})
+	if err := rootCmd.Execute(); !errors.Is(err, phc.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for an 8 byte key, got %v", err)
+	}
+}
+
+func TestPHCBench(t *testing.T) {
+	resetFlags(t, phcBenchCmd)
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	defer rootCmd.SetOut(nil)
+
+	rootCmd.SetArgs([]string{"phc", "bench", "--target", "1ms",
+		"--min-memory", "64", "--max-memory", "128", "--max-iterations", "2", "--parallelism", "1"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("rum phc bench: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 { // header, 4 measurements, recommendation
+		t.Fatalf("expected 6 lines, got:\n%s", out.String())
+	}
+	if !strings.HasPrefix(lines[5], "Recommended: memory=") {
+		t.Errorf("missing recommendation line, got %q", lines[5])
+	}
+}
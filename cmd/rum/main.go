@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
@@ -13,6 +16,14 @@ import (
 var (
 	version = "dev"
 	cfgFile string
+
+	genWatch       bool
+	genSkipInvalid bool
+
+	renderData   string
+	renderOutDir string
+	renderEOL    string
+	renderWatch  bool
 )
 
 func main() {
@@ -35,8 +46,10 @@ var genCmd = &cobra.Command{
 	Short:   "Generate code from rum.yaml configuration",
 	Long: `Generate code based on the rum.yaml configuration file.
 
-This command reads the rum.yaml file in the current directory and generates
-code for all configured components (templates, services, etc.).
+This command reads the nearest rum.yaml, searching from the current directory
+upwards, and generates code for all configured components (templates,
+services, etc.). With --watch it keeps running and regenerates templates
+whenever a template file is added, removed or changed.
 
 Example rum.yaml:
 
@@ -70,24 +83,81 @@ var initCmd = &cobra.Command{
 	RunE:  runInit,
 }
 
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render all templates to static files",
+	Long: `Render every template configured in rum.yaml into the output directory.
+
+Output paths mirror the template paths with the .tmpl extension removed.
+Templates receive the whole data file, unless it has a top-level key equal
+to the template's constant name, in which case only that value is used.
+A sibling data file (home.html.tmpl -> home.html.data.yaml or .json) is
+merged over that data, so each page can override individual keys.
+
+With --watch it keeps running, and when a template changes renders it again
+together with every template that includes it, directly or through other
+partials.
+
+Example:
+  rum render --data data.json --out dist/
+`,
+	RunE: runRender,
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "rum.yaml", "config file path")
+	genCmd.Flags().BoolVarP(&genWatch, "watch", "w", false, "regenerate whenever a template changes (stop with Ctrl+C)")
+	genCmd.Flags().BoolVar(&genSkipInvalid, "skip-invalid", false, "skip templates that fail to parse instead of failing (overrides skip_invalid)")
+	renderCmd.Flags().StringVar(&renderData, "data", "", "JSON or YAML data file passed to templates")
+	renderCmd.Flags().StringVar(&renderOutDir, "out", "dist", "output directory")
+	renderCmd.Flags().StringVar(&renderEOL, "line-endings", "", `normalize text output to "lf" or "crlf" (overrides line_endings)`)
+	renderCmd.Flags().BoolVarP(&renderWatch, "watch", "w", false, "render changed templates and their dependents again (stop with Ctrl+C)")
 	rootCmd.AddCommand(genCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(renderCmd)
+}
+
+// loadConfig loads the --config file when given explicitly, and otherwise
+// searches for rum.yaml from the current directory upwards.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	if cmd.Flags().Changed("config") {
+		return config.Load(cfgFile)
+	}
+	return config.LoadDefault()
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load(cfgFile)
+	cfg, err := loadConfig(cmd)
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
 
 	generated := false
+	templateSets := cfg.TemplateSets()
+	if cmd.Flags().Changed("skip-invalid") {
+		for _, templates := range templateSets {
+			templates.SkipInvalid = genSkipInvalid
+		}
+	}
+
+	if len(templateSets) > 1 {
+		if err := generator.CheckOutputs(templateSets); err != nil {
+			return fmt.Errorf("generating templates: %w", err)
+		}
+	}
 
-	// Generate templates if configured
-	if cfg.HasTemplates() {
+	if genWatch {
+		if len(templateSets) == 0 {
+			return fmt.Errorf("no templates configured in %s", cfgFile)
+		}
+		fmt.Println("Watching templates... (press Ctrl+C to stop)")
+		return watchTemplates(cmd.Context(), templateSets)
+	}
+
+	// Generate templates if configured, including those of included files
+	for _, templates := range templateSets {
 		fmt.Println("Generating templates...")
-		gen := generator.NewTemplatesGenerator(cfg.Templates)
+		gen := generator.NewTemplatesGenerator(templates)
 		if err := gen.Generate(); err != nil {
 			return fmt.Errorf("generating templates: %w", err)
 		}
@@ -106,6 +176,64 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// watchTemplates runs a watcher per template set until all of them stop,
+// stopping the others as soon as one fails.
+func watchTemplates(ctx context.Context, sets []*config.TemplatesConfig) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(sets))
+	for _, templates := range sets {
+		go func() {
+			errs <- generator.NewWatcher(templates, generator.DefaultWatchInterval).WatchWithSignals(ctx)
+		}()
+	}
+
+	var firstErr error
+	for range sets {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	return firstErr
+}
+
+func runRender(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if !cfg.HasTemplates() {
+		return fmt.Errorf("no templates configured in %s", cfgFile)
+	}
+
+	if cmd.Flags().Changed("line-endings") {
+		cfg.Templates.LineEndings = renderEOL
+	}
+
+	data := map[string]any{}
+	if renderData != "" {
+		data, err = generator.LoadData(renderData)
+		if err != nil {
+			return fmt.Errorf("loading data: %w", err)
+		}
+	}
+
+	renderer := generator.NewStaticRenderer(cfg.Templates, renderOutDir)
+	if renderWatch {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		fmt.Println("Watching templates... (press Ctrl+C to stop)")
+		return renderer.Watch(ctx, data, generator.DefaultWatchInterval)
+	}
+
+	if err := renderer.Render(data); err != nil {
+		return fmt.Errorf("rendering templates: %w", err)
+	}
+	return nil
+}
+
 func runInit(cmd *cobra.Command, args []string) error {
 	if _, err := os.Stat(cfgFile); err == nil {
 		return fmt.Errorf("%s already exists", cfgFile)
@@ -113,16 +241,73 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	sample := `# Rum configuration file
 # Documentation: https://github.com/4Sigma/rum
+# Reference environment variables in this file as ${VAR} (unset ones are
+# an error)
+# expand_env: true
+
+# Further config files whose templates sections are generated too
+# include: ["team-a/rum.yaml", "team-b/rum.yaml"]
 
 # Template generation configuration
 templates:
   # Root directory where templates_gen.go will be generated
   root: "."
+  # Resolve root against the config file's directory ("config") or the
+  # nearest go.mod ("module")
+  # relative_to: "config"
   # Package name for generated code
   package: "main"
   # Template directories (glob patterns, supports **)
   dirs:
     - "templates/**/*.tmpl"
+  # Generate each subdirectory of root as its own package (named after the
+  # directory), resolving dirs inside each one
+  # package_per_dir: true
+  # Skip dirs that do not exist instead of failing
+  # allow_missing_dirs: true
+  # Leave templates that fail to parse out of the generated code instead of
+  # failing (also --skip-invalid)
+  # skip_invalid: true
+  # Leading path segments dropped from constant names
+  # strip_prefixes: ["templates/", "template/"]
+  # Prefix for constant names that would start with a digit (default "N")
+  # numeric_prefix: "N"
+  # Fail instead of warning when a template file is empty
+  # error_empty: false
+  # Register built-in template helpers (upper, lower, title, trim, default, join, date)
+  # builtins: false
+  # Parse templates on first use and return errors instead of panicking in init()
+  # lazy: false
+  # Generate a NewManager() constructor instead of a global Manager (not with lazy)
+  # constructor: false
+  # Generate typed Render<Const>(renderer, data) functions for matching templates
+  # data_types:
+  #   "templates/emails/*.tmpl": "models.Email"
+  # data_imports:
+  #   - "example.com/app/models"
+  # Embed static files (not parsed as templates) and generate an Assets() fs.FS accessor
+  # assets:
+  #   - "static/*.css"
+  # Generate a Templates() accessor exposing the embedded files as an fs.FS
+  # expose_fs: false
+  # Also write templates_manifest.json (path, const name and size of each template)
+  # emit_manifest: false
+  # Write constants to one templates_<dir>_gen.go file per top-level directory
+  # split_by_dir: false
+  # Line endings for text files written by rum render: "lf" or "crlf"
+  # line_endings: "lf"
+  # Use exactly the templates listed in this file (one path per line, in order)
+  # instead of globbing dirs
+  # manifest: "templates.list"
+  # Runtime template names: "path" (templates/pages/home.html.tmpl), "base"
+  # (home.html.tmpl) or "flat" (pages_home.html.tmpl); constants follow suit
+  # name_style: "path"
+  # When several files {{define}} the same name, the last one parsed wins:
+  # "warn" (default), "error" or "ignore"
+  # duplicate_defines: "warn"
+  # Keep a leading UTF-8 byte order mark in template files instead of
+  # stripping it from the output
+  # keep_bom: false
 
 # Future components (not yet implemented):
 # services:
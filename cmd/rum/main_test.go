@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateWithIncludes(t *testing.T) {
+	dir := t.TempDir()
+	for _, team := range []string{"team-a", "team-b"} {
+		os.MkdirAll(filepath.Join(dir, team, "templates"), 0755)
+		os.WriteFile(filepath.Join(dir, team, "templates", team+".html.tmpl"), []byte(team), 0644)
+		os.WriteFile(filepath.Join(dir, team, "rum.yaml"), []byte("templates:\n  package: \"main\"\n  dirs: [\"templates/*.tmpl\"]\n"), 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "rum.yaml"), []byte("include:\n  - team-a/rum.yaml\n  - team-b/rum.yaml\n"), 0644)
+	t.Chdir(dir)
+
+	resetConfigFlag(t)
+	rootCmd.SetArgs([]string{"gen", "--config", "rum.yaml"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("rum gen: %v", err)
+	}
+
+	for _, team := range []string{"team-a", "team-b"} {
+		content, err := os.ReadFile(filepath.Join(dir, team, "templates_gen.go"))
+		if err != nil {
+			t.Fatalf("%s: reading output: %v", team, err)
+		}
+		if !strings.Contains(string(content), "templates/"+team+".html.tmpl") {
+			t.Errorf("%s output does not list its template:\n%s", team, content)
+		}
+	}
+}
+
+func TestGenerateConfigOutsideWorkingDir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte("home"), 0644)
+	os.WriteFile(filepath.Join(dir, "rum.yaml"), []byte("templates:\n  package: \"main\"\n  dirs: [\"templates/*.tmpl\"]\n"), 0644)
+	t.Chdir(t.TempDir())
+
+	resetConfigFlag(t)
+	rootCmd.SetArgs([]string{"gen", "-c", filepath.Join(dir, "rum.yaml")})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("rum gen: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "templates_gen.go")); err != nil {
+		t.Errorf("expected output next to the config file: %v", err)
+	}
+}
+
+func TestGenerateWithIncludesFromOtherDir(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	os.MkdirAll(filepath.Join(sub, "team-a", "templates"), 0755)
+	os.WriteFile(filepath.Join(sub, "team-a", "templates", "a.html.tmpl"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(sub, "team-a", "rum.yaml"), []byte("templates:\n  package: \"main\"\n  dirs: [\"templates/*.tmpl\"]\n"), 0644)
+	os.WriteFile(filepath.Join(sub, "rum.yaml"), []byte("include:\n  - team-a/rum.yaml\n"), 0644)
+	t.Chdir(dir)
+
+	resetConfigFlag(t)
+	rootCmd.SetArgs([]string{"gen", "-c", "sub/rum.yaml"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("rum gen: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(sub, "team-a", "templates_gen.go")); err != nil {
+		t.Errorf("expected output in the included file's directory: %v", err)
+	}
+}
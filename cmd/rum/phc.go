@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/4Sigma/rum/crypto/phc"
+)
+
+var (
+	benchTarget        time.Duration
+	benchMinMemory     uint32
+	benchMaxMemory     uint32
+	benchMaxIterations uint32
+	benchParallelism   uint8
+)
+
+var phcCmd = &cobra.Command{
+	Use:   "phc",
+	Short: "Password hashing utilities",
+}
+
+var phcBenchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure argon2id settings on this machine and recommend one",
+	Long: `Hash once with every combination of memory (doubling from --min-memory to
+--max-memory, in KiB) and iterations (1 to --max-iterations), print the
+measured times, and recommend the strongest setting that hashes within
+--target. Run it on the hardware that will verify passwords.
+
+Example:
+  rum phc bench --target 500ms --parallelism 4
+`,
+	Args: cobra.NoArgs,
+	RunE: runPHCBench,
+}
+
+func init() {
+	phcBenchCmd.Flags().DurationVar(&benchTarget, "target", 250*time.Millisecond, "maximum time a single hash may take")
+	phcBenchCmd.Flags().Uint32Var(&benchMinMemory, "min-memory", 16*1024, "smallest memory to try, in KiB")
+	phcBenchCmd.Flags().Uint32Var(&benchMaxMemory, "max-memory", 256*1024, "largest memory to try, in KiB")
+	phcBenchCmd.Flags().Uint32Var(&benchMaxIterations, "max-iterations", 4, "largest number of iterations to try")
+	phcBenchCmd.Flags().Uint8Var(&benchParallelism, "parallelism", 2, "number of lanes")
+	phcCmd.AddCommand(phcBenchCmd)
+	rootCmd.AddCommand(phcCmd)
+}
+
+func runPHCBench(cmd *cobra.Command, args []string) error {
+	if benchMinMemory == 0 || benchMinMemory > benchMaxMemory {
+		return fmt.Errorf("--min-memory must be between 1 and --max-memory")
+	}
+
+	var memories, iterations []uint32
+	for m := benchMinMemory; m <= benchMaxMemory && m != 0; m *= 2 {
+		memories = append(memories, m)
+	}
+	for i := uint32(1); i <= benchMaxIterations; i++ {
+		iterations = append(iterations, i)
+	}
+
+	res, err := phc.TuneArgon2(benchTarget, memories, iterations, benchParallelism)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "memory (KiB)\titerations\tparallelism\ttime\t")
+	for _, r := range res.Results {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t\n", r.Memory, r.Iterations, r.Parallelism, r.Duration.Round(time.Microsecond))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	best := res.Best
+	if res.WithinTarget {
+		fmt.Fprintf(out, "Recommended: memory=%d iterations=%d parallelism=%d (%s, target %s)\n",
+			best.Memory, best.Iterations, best.Parallelism, best.Duration.Round(time.Microsecond), benchTarget)
+	} else {
+		fmt.Fprintf(out, "Recommended: memory=%d iterations=%d parallelism=%d (%s); no setting hashed within %s, this is the fastest measured\n",
+			best.Memory, best.Iterations, best.Parallelism, best.Duration.Round(time.Microsecond), benchTarget)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package block_cipher
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrUnsafePath = errors.New("archive entry path escapes the destination")
+
+// EncryptArchive writes the named files as a tar archive encrypted with
+// EncryptStream. Entries are named by their slash-separated path as given,
+// without any leading "/" or ".." segments, so relative paths are preserved
+// on extraction and the archive never escapes DecryptArchive's destination:
+// "../shared/a.txt" is stored as "shared/a.txt".
+func EncryptArchive(w io.Writer, files []string, password []byte) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, files))
+	}()
+
+	err := EncryptStream(w, pr, password)
+	pr.CloseWithError(err)
+	return err
+}
+
+// writeTar writes files as a tar archive to w.
+func writeTar(w io.Writer, files []string) error {
+	tw := tar.NewWriter(w)
+	for _, name := range files {
+		if err := addTarFile(tw, name); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func addTarFile(tw *tar.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", name)
+	}
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = archiveName(name)
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write archive header for %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// archiveName converts a file path to a local, slash-separated entry name by
+// dropping the volume, leading "/" and leading ".." segments.
+func archiveName(name string) string {
+	entry := strings.TrimLeft(path.Clean(filepath.ToSlash(strings.TrimPrefix(name, filepath.VolumeName(name)))), "/")
+	for entry == ".." || strings.HasPrefix(entry, "../") {
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, ".."), "/")
+	}
+	return entry
+}
+
+// DecryptArchive decrypts an archive written by EncryptArchive and extracts
+// its files into destDir. Entries with absolute paths or ".." segments are
+// rejected with ErrUnsafePath before anything is written for them. On error
+// destDir may hold the files extracted so far.
+func DecryptArchive(r io.Reader, destDir string, password []byte) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(DecryptStream(pw, r, password))
+	}()
+	defer pr.Close()
+
+	if err := extractTar(pr, destDir); err != nil {
+		return err
+	}
+
+	// Drain the padding after the end of the archive so a corrupt final
+	// block is reported.
+	_, err := io.Copy(io.Discard, pr)
+	return err
+}
+
+// extractTar writes the regular files of the tar stream r below destDir.
+func extractTar(r io.Reader, destDir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("%w: %q", ErrUnsafePath, hdr.Name)
+		}
+		target := filepath.Join(destDir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractTarFile(tr, target, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported archive entry %q", hdr.Name)
+		}
+	}
+}
+
+func extractTarFile(r io.Reader, target string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to extract %s: %w", target, err)
+	}
+	return f.Close()
+}
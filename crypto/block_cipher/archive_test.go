@@ -0,0 +1,117 @@
+package block_cipher
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	t.Chdir(src)
+	os.MkdirAll(filepath.Join("data", "nested"), 0755)
+	os.WriteFile(filepath.Join("data", "users.csv"), []byte("id,name\n1,Ada\n"), 0644)
+	os.WriteFile(filepath.Join("data", "nested", "notes.txt"), bytes.Repeat([]byte("note "), 1000), 0600)
+
+	password := []byte("archive password")
+	var encrypted bytes.Buffer
+	files := []string{filepath.Join("data", "users.csv"), filepath.Join("data", "nested", "notes.txt")}
+	if err := EncryptArchive(&encrypted, files, password); err != nil {
+		t.Fatalf("EncryptArchive error: %v", err)
+	}
+	if !bytes.HasPrefix(encrypted.Bytes(), []byte(magicHeader)) {
+		t.Error("archive is not in the encrypted format")
+	}
+
+	dest := t.TempDir()
+	if err := DecryptArchive(bytes.NewReader(encrypted.Bytes()), dest, password); err != nil {
+		t.Fatalf("DecryptArchive error: %v", err)
+	}
+
+	for _, name := range files {
+		want, _ := os.ReadFile(name)
+		got, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatalf("reading extracted %s: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: extracted content differs", name)
+		}
+	}
+
+	info, _ := os.Stat(filepath.Join(dest, "data", "nested", "notes.txt"))
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestArchiveRoundTripParentPaths(t *testing.T) {
+	base := t.TempDir()
+	os.MkdirAll(filepath.Join(base, "shared"), 0755)
+	os.MkdirAll(filepath.Join(base, "work"), 0755)
+	os.WriteFile(filepath.Join(base, "shared", "config.txt"), []byte("shared config"), 0644)
+	t.Chdir(filepath.Join(base, "work"))
+
+	password := []byte("archive password")
+	var encrypted bytes.Buffer
+	if err := EncryptArchive(&encrypted, []string{filepath.Join("..", "shared", "config.txt")}, password); err != nil {
+		t.Fatalf("EncryptArchive error: %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := DecryptArchive(bytes.NewReader(encrypted.Bytes()), dest, password); err != nil {
+		t.Fatalf("DecryptArchive error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "shared", "config.txt"))
+	if err != nil {
+		t.Fatalf("reading extracted file: %v", err)
+	}
+	if string(got) != "shared config" {
+		t.Errorf("extracted content = %q, want %q", got, "shared config")
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"data/users.csv", "data/users.csv"},
+		{"/etc/hosts", "etc/hosts"},
+		{"../x", "x"},
+		{"../../a/../b/c", "b/c"},
+		{"a/../../b", "b"},
+	}
+
+	for _, tt := range tests {
+		if got := archiveName(filepath.FromSlash(tt.name)); got != tt.want {
+			t.Errorf("archiveName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecryptArchiveRejectsTraversal(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	tw.WriteHeader(&tar.Header{Name: "../escape.txt", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+	tw.Write([]byte("evil"))
+	tw.Close()
+
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, &archive, []byte("pw")); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	parent := t.TempDir()
+	dest := filepath.Join(parent, "out")
+	err := DecryptArchive(&encrypted, dest, []byte("pw"))
+	if !errors.Is(err, ErrUnsafePath) {
+		t.Fatalf("expected ErrUnsafePath, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "escape.txt")); !os.IsNotExist(err) {
+		t.Error("traversal entry was written outside the destination")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/aes"
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -368,3 +369,45 @@ func calculateMD5(filename string) ([]byte, error) {
 
 	return hashInBytes, nil
 }
+
+func TestEncryptStreamDeterministicRandReader(t *testing.T) {
+	defer func() { randReader = rand.Reader }()
+
+	var outputs [][]byte
+	for range 2 {
+		randReader = bytes.NewReader(bytes.Repeat([]byte{0x42}, saltSize))
+
+		var out bytes.Buffer
+		if err := EncryptStream(&out, bytes.NewReader([]byte("plain text")), []byte("s3cr3t")); err != nil {
+			t.Fatalf("EncryptStream error: %v", err)
+		}
+		outputs = append(outputs, out.Bytes())
+	}
+
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Error("expected reproducible ciphertext with a fixed random source")
+	}
+}
+
+func TestEncryptStreamHashed(t *testing.T) {
+	plain := bytes.Repeat([]byte("rum backup "), bufferSize/8)
+
+	var encrypted bytes.Buffer
+	digest, err := EncryptStreamHashed(&encrypted, bytes.NewReader(plain), []byte("s3cr3t"))
+	if err != nil {
+		t.Fatalf("EncryptStreamHashed error: %v", err)
+	}
+
+	want := sha256.Sum256(plain)
+	if !bytes.Equal(digest, want[:]) {
+		t.Errorf("digest = %x, want %x", digest, want)
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptStream(&decrypted, &encrypted, []byte("s3cr3t")); err != nil {
+		t.Fatalf("DecryptStream error: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plain) {
+		t.Error("decrypted data does not match the input")
+	}
+}
@@ -30,6 +30,15 @@ const (
 	ivEndOffset = 48
 )
 
+var (
+	ErrInvalidPadding = errors.New("invalid padding: wrong password or corrupted data")
+	ErrTruncated      = errors.New("encrypted data is truncated")
+)
+
+// randReader is the source of salts. Tests may replace it with a
+// deterministic reader; production code must leave it as crypto/rand.
+var randReader io.Reader = rand.Reader
+
 func readAndValidateHeader(inputFile io.Reader) ([]byte, error) {
 	header := make([]byte, headerSize)
 	_, err := io.ReadFull(inputFile, header)
@@ -51,15 +60,22 @@ func deriveKeyAndIV(password, salt []byte) ([]byte, []byte) {
 	return key, iv
 }
 
-func removePKCS7Padding(data []byte, bytesRead int) []byte {
+// removePKCS7Padding strips and validates the PKCS#7 padding of the final
+// decrypted data. Invalid padding almost always means a wrong password.
+func removePKCS7Padding(data []byte, bytesRead int) ([]byte, error) {
 	if bytesRead == 0 {
-		return data
+		return nil, ErrTruncated
 	}
 	paddingLength := int(data[bytesRead-1])
-	if paddingLength > bytesRead || paddingLength > aes.BlockSize {
-		return data
+	if paddingLength == 0 || paddingLength > bytesRead || paddingLength > aes.BlockSize {
+		return nil, ErrInvalidPadding
+	}
+	for _, b := range data[bytesRead-paddingLength : bytesRead] {
+		if int(b) != paddingLength {
+			return nil, ErrInvalidPadding
+		}
 	}
-	return data[:bytesRead-paddingLength]
+	return data[:bytesRead-paddingLength], nil
 }
 
 // processDecryptionBlock handles decryption and writing of a single block
@@ -69,6 +85,10 @@ func processDecryptionBlock(
 	isLastBlock bool, previousDecryptedData []byte,
 ) ([]byte, error) {
 
+	if bytesRead%aes.BlockSize != 0 {
+		return nil, ErrTruncated
+	}
+
 	currentDecrypted := make([]byte, bytesRead)
 	mode.CryptBlocks(currentDecrypted, encryptedBuffer[:bytesRead])
 
@@ -79,7 +99,10 @@ func processDecryptionBlock(
 	}
 
 	if isLastBlock {
-		finalData := removePKCS7Padding(currentDecrypted, bytesRead)
+		finalData, err := removePKCS7Padding(currentDecrypted, bytesRead)
+		if err != nil {
+			return nil, err
+		}
 		if _, err := outputFile.Write(finalData); err != nil {
 			return nil, fmt.Errorf("failed to write final block: %w", err)
 		}
@@ -92,11 +115,12 @@ func processDecryptionBlock(
 }
 
 func handleEndOfFile(outputFile io.Writer, previousDecryptedData []byte) error {
-	if len(previousDecryptedData) > 0 {
-		finalData := removePKCS7Padding(previousDecryptedData, len(previousDecryptedData))
-		if _, err := outputFile.Write(finalData); err != nil {
-			return fmt.Errorf("failed to write final block: %w", err)
-		}
+	finalData, err := removePKCS7Padding(previousDecryptedData, len(previousDecryptedData))
+	if err != nil {
+		return err
+	}
+	if _, err := outputFile.Write(finalData); err != nil {
+		return fmt.Errorf("failed to write final block: %w", err)
 	}
 	return nil
 }
@@ -121,6 +145,9 @@ func DecryptStream(outputFile io.Writer, inputFile io.Reader, password []byte) e
 	for {
 		bytesRead, readErr := io.ReadFull(inputFile, encryptedBuffer)
 		isEOF := readErr == io.EOF || readErr == io.ErrUnexpectedEOF
+		if readErr != nil && !isEOF {
+			return fmt.Errorf("failed to read encrypted data: %w", readErr)
+		}
 		isLastBlock := bytesRead < bufferSize || isEOF
 
 		if bytesRead == 0 {
@@ -137,16 +164,12 @@ func DecryptStream(outputFile io.Writer, inputFile io.Reader, password []byte) e
 		}
 
 		previousDecryptedData = nextPreviousData
-
-		if readErr != nil && !isEOF {
-			return fmt.Errorf("failed to read encrypted data: %w", readErr)
-		}
 	}
 }
 
 func writeEncryptedHeader(w io.Writer) ([]byte, error) {
 	salt := make([]byte, saltSize)
-	_, err := io.ReadFull(rand.Reader, salt)
+	_, err := io.ReadFull(randReader, salt)
 	if err != nil {
 		return nil, fmt.Errorf("error generating salt: %w", err)
 	}
@@ -213,6 +236,11 @@ func EncryptStream(w io.Writer, r io.Reader, password []byte) error {
 		return err
 	}
 
+	return encryptCBC(w, r, cbc)
+}
+
+// encryptCBC encrypts r into w with cbc, applying PKCS#7 padding at the end.
+func encryptCBC(w io.Writer, r io.Reader, cbc cipher.BlockMode) error {
 	readBuffer := make([]byte, bufferSize)
 	hasWrittenData := false
 
@@ -220,30 +248,23 @@ func EncryptStream(w io.Writer, r io.Reader, password []byte) error {
 		bytesRead, readErr := io.ReadFull(r, readBuffer)
 
 		isEOF := readErr == io.EOF || readErr == io.ErrUnexpectedEOF
+		if readErr != nil && !isEOF {
+			return fmt.Errorf("failed to read input data: %w", readErr)
+		}
 		isLastBlock := bytesRead < bufferSize || isEOF
 
 		if isLastBlock {
 			// Process the final block with proper padding
-			err = processFinalBlock(w, cbc, readBuffer, bytesRead, hasWrittenData)
-			if err != nil {
-				return err
-			}
-			break
+			return processFinalBlock(w, cbc, readBuffer, bytesRead, hasWrittenData)
 		}
 
-		err = writeEncryptedBlock(w, cbc, readBuffer[:bytesRead])
+		err := writeEncryptedBlock(w, cbc, readBuffer[:bytesRead])
 		if err != nil {
 			return err
 		}
 
 		hasWrittenData = true
-
-		if readErr != nil && !isEOF {
-			return fmt.Errorf("failed to read input data: %w", readErr)
-		}
 	}
-
-	return nil
 }
 
 func applyPKCS7Padding(data []byte) []byte {
@@ -251,3 +272,13 @@ func applyPKCS7Padding(data []byte) []byte {
 	paddingBytes := bytes.Repeat([]byte{byte(padding)}, padding)
 	return append(data, paddingBytes...)
 }
+
+// EncryptStreamHashed encrypts like EncryptStream and also returns the
+// SHA-256 digest of the plaintext, computed in the same pass over r.
+func EncryptStreamHashed(w io.Writer, r io.Reader, password []byte) (plaintextSHA256 []byte, err error) {
+	hasher := sha256.New()
+	if err := EncryptStream(w, io.TeeReader(r, hasher), password); err != nil {
+		return nil, err
+	}
+	return hasher.Sum(nil), nil
+}
@@ -0,0 +1,163 @@
+package block_cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrInputTooShort = errors.New("ciphertext stealing requires at least 16 bytes of input")
+)
+
+// CBCOptions configures EncryptStreamWithOptions and DecryptStreamWithOptions.
+// A nil *CBCOptions behaves exactly like EncryptStream and DecryptStream.
+type CBCOptions struct {
+	// CiphertextStealing replaces PKCS#7 padding with CBC ciphertext stealing
+	// (the CS3 variant), so the ciphertext is exactly as long as the
+	// plaintext. Inputs must be at least one block (16 bytes) long. The
+	// result keeps the "Salted__" header but cannot be decrypted by OpenSSL.
+	CiphertextStealing bool
+}
+
+func (o *CBCOptions) stealing() bool {
+	return o != nil && o.CiphertextStealing
+}
+
+// EncryptStreamWithOptions encrypts r into w like EncryptStream, applying opts.
+func EncryptStreamWithOptions(w io.Writer, r io.Reader, password []byte, opts *CBCOptions) error {
+	if !opts.stealing() {
+		return EncryptStream(w, r, password)
+	}
+
+	salt, err := writeEncryptedHeader(w)
+	if err != nil {
+		return err
+	}
+
+	key, iv := deriveKeyAndIV(password, salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("error creating AES cipher: %w", err)
+	}
+	cbc := cipher.NewCBCEncrypter(block, iv)
+
+	return streamCTS(w, r, cbc, func(tail []byte) []byte {
+		return encryptCTSTail(cbc, tail)
+	})
+}
+
+// DecryptStreamWithOptions decrypts r into w like DecryptStream, applying opts.
+func DecryptStreamWithOptions(w io.Writer, r io.Reader, password []byte, opts *CBCOptions) error {
+	if !opts.stealing() {
+		return DecryptStream(w, r, password)
+	}
+
+	salt, err := readAndValidateHeader(r)
+	if err != nil {
+		return err
+	}
+
+	key, iv := deriveKeyAndIV(password, salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("failed to create cipher: %w", err)
+	}
+	cbc := cipher.NewCBCDecrypter(block, iv)
+
+	return streamCTS(w, r, cbc, func(tail []byte) []byte {
+		return decryptCTSTail(block, cbc, tail)
+	})
+}
+
+// streamCTS runs r through mode, holding back the last one to two blocks
+// (at least one full block plus one byte, unless the input is a single block)
+// for finish, which applies ciphertext stealing.
+func streamCTS(w io.Writer, r io.Reader, mode cipher.BlockMode, finish func(tail []byte) []byte) error {
+	const bs = aes.BlockSize
+
+	chunk := make([]byte, bufferSize)
+	pending := make([]byte, 0, bufferSize+2*bs)
+
+	for {
+		n, readErr := io.ReadFull(r, chunk)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read input data: %w", readErr)
+		}
+		pending = append(pending, chunk[:n]...)
+
+		if len(pending) > 2*bs {
+			blocks := (len(pending)-2*bs-1)/bs + 1
+			out := make([]byte, blocks*bs)
+			mode.CryptBlocks(out, pending[:blocks*bs])
+			if _, err := w.Write(out); err != nil {
+				return fmt.Errorf("error writing block: %w", err)
+			}
+			pending = append(pending[:0], pending[blocks*bs:]...)
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	if len(pending) < bs {
+		return ErrInputTooShort
+	}
+	if _, err := w.Write(finish(pending)); err != nil {
+		return fmt.Errorf("error writing final block: %w", err)
+	}
+	return nil
+}
+
+// encryptCTSTail encrypts the final one to two blocks with CS3 stealing:
+// the last, zero-padded block is encrypted normally and emitted first,
+// followed by the leading bytes of the penultimate ciphertext block.
+func encryptCTSTail(cbc cipher.BlockMode, tail []byte) []byte {
+	const bs = aes.BlockSize
+	if len(tail) == bs {
+		out := make([]byte, bs)
+		cbc.CryptBlocks(out, tail)
+		return out
+	}
+
+	d := len(tail) - bs
+	penultimate := make([]byte, bs)
+	cbc.CryptBlocks(penultimate, tail[:bs])
+
+	last := make([]byte, bs)
+	copy(last, tail[bs:])
+	cbc.CryptBlocks(last, last)
+
+	return append(last, penultimate[:d]...)
+}
+
+// decryptCTSTail reverses encryptCTSTail. The raw block cipher recovers the
+// stolen bytes of the penultimate ciphertext block, which is then decrypted
+// through cbc to keep the chaining intact.
+func decryptCTSTail(block cipher.Block, cbc cipher.BlockMode, tail []byte) []byte {
+	const bs = aes.BlockSize
+	if len(tail) == bs {
+		out := make([]byte, bs)
+		cbc.CryptBlocks(out, tail)
+		return out
+	}
+
+	d := len(tail) - bs
+	decrypted := make([]byte, bs)
+	block.Decrypt(decrypted, tail[:bs])
+
+	penultimate := make([]byte, bs)
+	copy(penultimate, tail[bs:])
+	copy(penultimate[d:], decrypted[d:])
+
+	last := make([]byte, d)
+	subtle.XORBytes(last, decrypted[:d], penultimate[:d])
+
+	out := make([]byte, bs, len(tail))
+	cbc.CryptBlocks(out, penultimate)
+	return append(out, last...)
+}
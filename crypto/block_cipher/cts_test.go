@@ -0,0 +1,79 @@
+package block_cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestCTSRoundTrip(t *testing.T) {
+	password := []byte("s3cr3t")
+	opts := &CBCOptions{CiphertextStealing: true}
+
+	sizes := []int{
+		aes.BlockSize,
+		aes.BlockSize + 1,
+		2*aes.BlockSize - 1,
+		2 * aes.BlockSize,
+		2*aes.BlockSize + 1,
+		1000,
+		bufferSize,
+		bufferSize + 1,
+		bufferSize + aes.BlockSize + 7,
+		3*bufferSize/2 + 5,
+	}
+
+	for _, size := range sizes {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		var encrypted bytes.Buffer
+		if err := EncryptStreamWithOptions(&encrypted, bytes.NewReader(plain), password, opts); err != nil {
+			t.Fatalf("size %d: encrypt error: %v", size, err)
+		}
+		if got := encrypted.Len() - headerSize; got != size {
+			t.Errorf("size %d: ciphertext length = %d, want %d", size, got, size)
+		}
+
+		var decrypted bytes.Buffer
+		if err := DecryptStreamWithOptions(&decrypted, &encrypted, password, opts); err != nil {
+			t.Fatalf("size %d: decrypt error: %v", size, err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plain) {
+			t.Errorf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestCTSSwapsLastBlocksOfCBC(t *testing.T) {
+	defer func() { randReader = rand.Reader }()
+
+	plain := bytes.Repeat([]byte("0123456789abcdef"), 4)
+	encrypt := func(opts *CBCOptions) []byte {
+		randReader = bytes.NewReader(bytes.Repeat([]byte{0x42}, saltSize))
+		var out bytes.Buffer
+		if err := EncryptStreamWithOptions(&out, bytes.NewReader(plain), []byte("s3cr3t"), opts); err != nil {
+			t.Fatalf("encrypt error: %v", err)
+		}
+		return out.Bytes()[headerSize:]
+	}
+
+	cbc := encrypt(nil)
+	cts := encrypt(&CBCOptions{CiphertextStealing: true})
+
+	// For block-aligned input CS3 is plain CBC with the last two blocks swapped.
+	want := append(append(append([]byte{}, cbc[:32]...), cbc[48:64]...), cbc[32:48]...)
+	if !bytes.Equal(cts, want) {
+		t.Errorf("CTS ciphertext does not match swapped CBC blocks")
+	}
+}
+
+func TestCTSInputTooShort(t *testing.T) {
+	opts := &CBCOptions{CiphertextStealing: true}
+	err := EncryptStreamWithOptions(&bytes.Buffer{}, bytes.NewReader([]byte("short")), []byte("s3cr3t"), opts)
+	if !errors.Is(err, ErrInputTooShort) {
+		t.Errorf("expected ErrInputTooShort, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package block_cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"io"
+)
+
+// DataKeySize is the size of the data-encryption keys made by NewDataKey,
+// suitable for AES-256.
+const DataKeySize = 32
+
+// NewDataKey generates a random data-encryption key (DEK) for envelope
+// encryption and wraps it with master, an AES key of 16, 24 or 32 bytes.
+// Store wrappedDEK next to the record it protects and discard plainDEK after
+// use; UnwrapDataKey recovers it. The wrapped form is a random GCM nonce
+// followed by the sealed key.
+func NewDataKey(master []byte) (plainDEK, wrappedDEK []byte, err error) {
+	aead, err := newKeyWrapGCM(master)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plainDEK = make([]byte, DataKeySize)
+	if _, err := io.ReadFull(randReader, plainDEK); err != nil {
+		return nil, nil, fmt.Errorf("error generating data key: %w", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(randReader, nonce); err != nil {
+		return nil, nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	wrappedDEK = aead.Seal(nonce, nonce, plainDEK, nil)
+	return plainDEK, wrappedDEK, nil
+}
+
+// UnwrapDataKey recovers a key wrapped by NewDataKey. It returns
+// ErrAuthFailed if wrappedDEK was tampered with or master is wrong.
+func UnwrapDataKey(master, wrappedDEK []byte) (plainDEK []byte, err error) {
+	aead, err := newKeyWrapGCM(master)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrappedDEK) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrTruncated
+	}
+	nonce, sealed := wrappedDEK[:aead.NonceSize()], wrappedDEK[aead.NonceSize():]
+
+	plainDEK, err = aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return plainDEK, nil
+}
+
+func newKeyWrapGCM(master []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(master)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,61 @@
+package block_cipher
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDataKeyRoundTrip(t *testing.T) {
+	master := bytes.Repeat([]byte{0x11}, 32)
+
+	plain, wrapped, err := NewDataKey(master)
+	if err != nil {
+		t.Fatalf("NewDataKey error: %v", err)
+	}
+	if len(plain) != DataKeySize {
+		t.Errorf("DEK length = %d, want %d", len(plain), DataKeySize)
+	}
+	if bytes.Contains(wrapped, plain) {
+		t.Error("wrapped key contains the plaintext key")
+	}
+
+	got, err := UnwrapDataKey(master, wrapped)
+	if err != nil {
+		t.Fatalf("UnwrapDataKey error: %v", err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Error("unwrapped key differs from the generated key")
+	}
+
+	other, _, _ := NewDataKey(master)
+	if bytes.Equal(other, plain) {
+		t.Error("two data keys are identical")
+	}
+}
+
+func TestUnwrapDataKeyTampered(t *testing.T) {
+	master := bytes.Repeat([]byte{0x22}, 32)
+	_, wrapped, err := NewDataKey(master)
+	if err != nil {
+		t.Fatalf("NewDataKey error: %v", err)
+	}
+
+	for i := range wrapped {
+		tampered := bytes.Clone(wrapped)
+		tampered[i] ^= 0x01
+		if _, err := UnwrapDataKey(master, tampered); !errors.Is(err, ErrAuthFailed) {
+			t.Fatalf("byte %d flipped: expected ErrAuthFailed, got %v", i, err)
+		}
+	}
+
+	if _, err := UnwrapDataKey(bytes.Repeat([]byte{0x33}, 32), wrapped); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("wrong master: expected ErrAuthFailed, got %v", err)
+	}
+	if _, err := UnwrapDataKey(master, wrapped[:10]); !errors.Is(err, ErrTruncated) {
+		t.Errorf("short input: expected ErrTruncated, got %v", err)
+	}
+	if _, _, err := NewDataKey([]byte("short")); err == nil {
+		t.Error("expected an error for an invalid master key length")
+	}
+}
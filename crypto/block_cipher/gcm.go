@@ -0,0 +1,229 @@
+package block_cipher
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Authenticated format layout:
+//
+//	magic "RUMG" | version (1 byte) | salt (16 bytes) | base nonce (12 or 16 bytes)
+//	segment 0 | segment 1 | ... | final segment
+//
+// The plaintext is split into gcmSegmentSize chunks, each sealed with AES-256-GCM
+// under a nonce derived from the base nonce and the segment counter. Every
+// segment authenticates the header, the caller's AAD and a flag marking the
+// final segment, so reordering, truncation and header tampering are detected.
+const (
+	gcmMagic       = "RUMG"
+	gcmVersion     = 1
+	gcmSaltSize    = 16
+	gcmNonceSize   = 12
+	gcmLongNonce   = 16
+	gcmHeaderSize  = len(gcmMagic) + 1 + gcmSaltSize + gcmNonceSize
+	gcmSegmentSize = 64 * 1024
+)
+
+var (
+	ErrAuthFailed           = errors.New("message authentication failed")
+	ErrUnsupportedNonceSize = errors.New("unsupported GCM nonce size")
+)
+
+// GCMOptions configures the authenticated format. A nil *GCMOptions uses defaults.
+type GCMOptions struct {
+	// AAD is associated data that is authenticated but not encrypted, binding
+	// the ciphertext to a context such as a filename or user ID. Decryption
+	// must use the same AAD.
+	AAD []byte
+	// NonceSize is the GCM nonce size in bytes: 12 (the default) or 16, for
+	// interop with producers using longer nonces. The size is not recorded in
+	// the output, so decryption must use the same value.
+	NonceSize int
+}
+
+func (o *GCMOptions) aad() []byte {
+	if o == nil {
+		return nil
+	}
+	return o.AAD
+}
+
+func (o *GCMOptions) nonceSize() (int, error) {
+	if o == nil || o.NonceSize == 0 {
+		return gcmNonceSize, nil
+	}
+	if o.NonceSize != gcmNonceSize && o.NonceSize != gcmLongNonce {
+		return 0, fmt.Errorf("%w %d: must be %d or %d", ErrUnsupportedNonceSize, o.NonceSize, gcmNonceSize, gcmLongNonce)
+	}
+	return o.NonceSize, nil
+}
+
+// EncryptStreamGCM encrypts r into w using the authenticated AES-GCM format.
+// Unlike EncryptStream, the output is not OpenSSL compatible.
+func EncryptStreamGCM(w io.Writer, r io.Reader, password []byte, opts *GCMOptions) error {
+	nonceSize, err := opts.nonceSize()
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, gcmHeaderSize-gcmNonceSize+nonceSize)
+	copy(header, gcmMagic)
+	header[len(gcmMagic)] = gcmVersion
+	if _, err := io.ReadFull(randReader, header[len(gcmMagic)+1:]); err != nil {
+		return fmt.Errorf("error generating salt and nonce: %w", err)
+	}
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("error writing header: %w", err)
+	}
+
+	aead, err := newGCM(password, header, nonceSize)
+	if err != nil {
+		return err
+	}
+
+	baseNonce := header[len(header)-nonceSize:]
+	aad := newSegmentAAD(header, opts.aad())
+	br := bufio.NewReader(r)
+	plain := make([]byte, gcmSegmentSize)
+
+	for counter := uint64(0); ; counter++ {
+		n, readErr := io.ReadFull(br, plain)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read input data: %w", readErr)
+		}
+
+		final := readErr != nil
+		if !final {
+			if _, err := br.Peek(1); err == io.EOF {
+				final = true
+			}
+		}
+
+		sealed := aead.Seal(nil, segmentNonce(baseNonce, counter), plain[:n], aad.with(final))
+		if _, err := w.Write(sealed); err != nil {
+			return fmt.Errorf("error writing encrypted segment: %w", err)
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// DecryptStreamGCM decrypts data produced by EncryptStreamGCM. Segments are
+// written as soon as they are authenticated, so on failure w may already
+// hold a verified prefix of the plaintext.
+func DecryptStreamGCM(w io.Writer, r io.Reader, password []byte, opts *GCMOptions) error {
+	nonceSize, err := opts.nonceSize()
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, gcmHeaderSize-gcmNonceSize+nonceSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(header[:len(gcmMagic)]) != gcmMagic {
+		return errors.New("invalid file format")
+	}
+	if header[len(gcmMagic)] != gcmVersion {
+		return fmt.Errorf("unsupported format version %d", header[len(gcmMagic)])
+	}
+
+	aead, err := newGCM(password, header, nonceSize)
+	if err != nil {
+		return err
+	}
+
+	baseNonce := header[len(header)-nonceSize:]
+	aad := newSegmentAAD(header, opts.aad())
+	br := bufio.NewReader(r)
+	sealed := make([]byte, gcmSegmentSize+aead.Overhead())
+
+	for counter := uint64(0); ; counter++ {
+		n, readErr := io.ReadFull(br, sealed)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read encrypted data: %w", readErr)
+		}
+
+		final := readErr != nil
+		if !final {
+			if _, err := br.Peek(1); err == io.EOF {
+				final = true
+			}
+		}
+
+		plain, err := aead.Open(nil, segmentNonce(baseNonce, counter), sealed[:n], aad.with(final))
+		if err != nil {
+			return ErrAuthFailed
+		}
+		if _, err := w.Write(plain); err != nil {
+			return fmt.Errorf("failed to write decrypted segment: %w", err)
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// newGCM derives the AES-256 key from password and the header's salt.
+func newGCM(password, header []byte, nonceSize int) (cipher.AEAD, error) {
+	salt := header[len(gcmMagic)+1 : len(gcmMagic)+1+gcmSaltSize]
+	key := pbkdf2.Key(password, salt, pbkdf2Iterations, aes256KeySize, sha256.New)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	var aead cipher.AEAD
+	if nonceSize == gcmNonceSize {
+		aead, err = cipher.NewGCM(block)
+	} else {
+		aead, err = cipher.NewGCMWithNonceSize(block, nonceSize)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return aead, nil
+}
+
+// segmentNonce derives the nonce of segment counter by XORing the big-endian
+// counter into the last 8 bytes of the random base nonce, so segment 0 uses
+// base unchanged. XOR with a fixed base is a bijection on the counter, so
+// distinct segments of one stream never share a nonce; the random base, with
+// the per-stream salt and key, keeps nonces apart across streams. base is
+// not modified.
+func segmentNonce(base []byte, counter uint64) []byte {
+	nonce := make([]byte, len(base))
+	copy(nonce, base)
+	tail := nonce[len(nonce)-8:]
+	binary.BigEndian.PutUint64(tail, binary.BigEndian.Uint64(tail)^counter)
+	return nonce
+}
+
+// segmentAAD is the associated data shared by all segments, minus the
+// trailing final-segment flag.
+type segmentAAD []byte
+
+func newSegmentAAD(header, aad []byte) segmentAAD {
+	buf := make([]byte, 0, len(header)+len(aad)+1)
+	buf = append(buf, header...)
+	buf = append(buf, aad...)
+	return buf
+}
+
+// with returns the AAD for a segment, appending the final-segment flag.
+func (a segmentAAD) with(final bool) []byte {
+	flag := byte(0)
+	if final {
+		flag = 1
+	}
+	return append(a[:len(a):len(a)], flag)
+}
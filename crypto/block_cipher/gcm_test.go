@@ -0,0 +1,154 @@
+package block_cipher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestGCMRoundTrip(t *testing.T) {
+	sizes := []int{0, 1, 15, 16, 17, gcmSegmentSize - 1, gcmSegmentSize, gcmSegmentSize + 1, 3*gcmSegmentSize + 7}
+	password := []byte("s3cr3t")
+
+	for _, size := range sizes {
+		t.Run(fmt.Sprintf("%d bytes", size), func(t *testing.T) {
+			plain := bytes.Repeat([]byte{0xA5}, size)
+
+			var encrypted bytes.Buffer
+			if err := EncryptStreamGCM(&encrypted, bytes.NewReader(plain), password, nil); err != nil {
+				t.Fatalf("EncryptStreamGCM error: %v", err)
+			}
+
+			var decrypted bytes.Buffer
+			if err := DecryptStreamGCM(&decrypted, &encrypted, password, nil); err != nil {
+				t.Fatalf("DecryptStreamGCM error: %v", err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), plain) {
+				t.Error("decrypted data does not match the input")
+			}
+		})
+	}
+}
+
+func TestGCMAAD(t *testing.T) {
+	password := []byte("s3cr3t")
+	plain := bytes.Repeat([]byte("record "), 1000)
+
+	var encrypted bytes.Buffer
+	opts := &GCMOptions{AAD: []byte("user:42/report.pdf")}
+	if err := EncryptStreamGCM(&encrypted, bytes.NewReader(plain), password, opts); err != nil {
+		t.Fatalf("EncryptStreamGCM error: %v", err)
+	}
+
+	t.Run("matching", func(t *testing.T) {
+		var decrypted bytes.Buffer
+		err := DecryptStreamGCM(&decrypted, bytes.NewReader(encrypted.Bytes()), password, &GCMOptions{AAD: []byte("user:42/report.pdf")})
+		if err != nil {
+			t.Fatalf("DecryptStreamGCM error: %v", err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plain) {
+			t.Error("decrypted data does not match the input")
+		}
+	})
+
+	t.Run("mismatched", func(t *testing.T) {
+		var decrypted bytes.Buffer
+		err := DecryptStreamGCM(&decrypted, bytes.NewReader(encrypted.Bytes()), password, &GCMOptions{AAD: []byte("user:43/report.pdf")})
+		if !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("expected ErrAuthFailed, got %v", err)
+		}
+		if decrypted.Len() != 0 {
+			t.Error("expected no plaintext to be written")
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		err := DecryptStreamGCM(&bytes.Buffer{}, bytes.NewReader(encrypted.Bytes()), password, nil)
+		if !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("expected ErrAuthFailed, got %v", err)
+		}
+	})
+}
+
+func TestGCMDetectsTruncation(t *testing.T) {
+	password := []byte("s3cr3t")
+	plain := bytes.Repeat([]byte{1}, 2*gcmSegmentSize+10)
+
+	var encrypted bytes.Buffer
+	if err := EncryptStreamGCM(&encrypted, bytes.NewReader(plain), password, nil); err != nil {
+		t.Fatalf("EncryptStreamGCM error: %v", err)
+	}
+
+	// Drop the final segment so the stream ends on a full, non-final segment.
+	truncated := encrypted.Bytes()[:gcmHeaderSize+2*(gcmSegmentSize+16)]
+	err := DecryptStreamGCM(&bytes.Buffer{}, bytes.NewReader(truncated), password, nil)
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected ErrAuthFailed, got %v", err)
+	}
+}
+
+func TestSegmentNonce(t *testing.T) {
+	base := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	original := bytes.Clone(base)
+
+	if got := segmentNonce(base, 0); !bytes.Equal(got, base) {
+		t.Errorf("counter 0 = %x, want the base nonce %x", got, base)
+	}
+
+	want1 := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10}
+	if got := segmentNonce(base, 1); !bytes.Equal(got, want1) {
+		t.Errorf("counter 1 = %x, want %x", got, want1)
+	}
+
+	seen := make(map[string]uint64)
+	counters := []uint64{0, 1, 2, 3, 255, 256, 1 << 32, 1<<64 - 1}
+	for c := range uint64(4096) {
+		counters = append(counters, c)
+	}
+	for _, c := range counters {
+		nonce := segmentNonce(base, c)
+		if len(nonce) != len(base) {
+			t.Fatalf("counter %d: nonce length %d", c, len(nonce))
+		}
+		if prev, ok := seen[string(nonce)]; ok && prev != c {
+			t.Fatalf("counters %d and %d share nonce %x", prev, c, nonce)
+		}
+		seen[string(nonce)] = c
+	}
+
+	if !bytes.Equal(base, original) {
+		t.Error("segmentNonce modified the base nonce")
+	}
+}
+
+func TestGCMNonceSize(t *testing.T) {
+	password := []byte("s3cr3t")
+	plain := bytes.Repeat([]byte("interop "), gcmSegmentSize/4)
+	long := &GCMOptions{NonceSize: 16}
+
+	var encrypted bytes.Buffer
+	if err := EncryptStreamGCM(&encrypted, bytes.NewReader(plain), password, long); err != nil {
+		t.Fatalf("EncryptStreamGCM error: %v", err)
+	}
+	if encrypted.Len() <= gcmHeaderSize+len(plain) {
+		t.Errorf("unexpected ciphertext size %d", encrypted.Len())
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptStreamGCM(&decrypted, bytes.NewReader(encrypted.Bytes()), password, long); err != nil {
+		t.Fatalf("DecryptStreamGCM error: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plain) {
+		t.Error("decrypted data differs from plaintext")
+	}
+
+	if err := DecryptStreamGCM(io.Discard, bytes.NewReader(encrypted.Bytes()), password, nil); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("default nonce size on 16-byte nonce data: expected ErrAuthFailed, got %v", err)
+	}
+
+	if err := EncryptStreamGCM(io.Discard, bytes.NewReader(plain), password, &GCMOptions{NonceSize: 8}); !errors.Is(err, ErrUnsupportedNonceSize) {
+		t.Errorf("expected ErrUnsupportedNonceSize, got %v", err)
+	}
+}
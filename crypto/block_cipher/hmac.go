@@ -0,0 +1,176 @@
+package block_cipher
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Encrypt-then-MAC format layout:
+//
+//	magic "RUMH" | version (1 byte) | salt (16 bytes)
+//	AES-256-CBC ciphertext with PKCS#7 padding
+//	HMAC-SHA256 tag (32 bytes) over the header and the ciphertext
+//
+// The AES key, the HMAC key and the IV are all derived from the password
+// and salt with PBKDF2.
+const (
+	hmacMagic      = "RUMH"
+	hmacVersion    = 1
+	hmacSaltSize   = 16
+	hmacHeaderSize = len(hmacMagic) + 1 + hmacSaltSize
+	hmacTagSize    = sha256.Size
+)
+
+// EncryptStreamHMAC encrypts r into w with AES-256-CBC and appends an
+// HMAC-SHA256 tag, so DecryptStreamHMAC can detect any modification.
+// Unlike EncryptStream, the output is not OpenSSL compatible.
+func EncryptStreamHMAC(w io.Writer, r io.Reader, password []byte) error {
+	header := make([]byte, hmacHeaderSize)
+	copy(header, hmacMagic)
+	header[len(hmacMagic)] = hmacVersion
+	if _, err := io.ReadFull(randReader, header[len(hmacMagic)+1:]); err != nil {
+		return fmt.Errorf("error generating salt: %w", err)
+	}
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("error writing header: %w", err)
+	}
+
+	block, mac, iv, err := newHMACCipher(password, header)
+	if err != nil {
+		return err
+	}
+
+	if err := encryptCBC(io.MultiWriter(w, mac), r, cipher.NewCBCEncrypter(block, iv)); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(mac.Sum(nil)); err != nil {
+		return fmt.Errorf("error writing authentication tag: %w", err)
+	}
+	return nil
+}
+
+// DecryptStreamHMAC decrypts data produced by EncryptStreamHMAC. The MAC is
+// computed while decrypting, so the input is streamed rather than buffered,
+// and the final block is written only after the tag has been verified:
+// tampering with the end of the data never reaches w. Earlier blocks,
+// however, are already written by the time the tag is checked, so on
+// ErrAuthFailed w may hold unauthenticated plaintext and must be discarded.
+func DecryptStreamHMAC(w io.Writer, r io.Reader, password []byte) error {
+	header := make([]byte, hmacHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(header[:len(hmacMagic)]) != hmacMagic {
+		return errors.New("invalid file format")
+	}
+	if header[len(hmacMagic)] != hmacVersion {
+		return fmt.Errorf("unsupported format version %d", header[len(hmacMagic)])
+	}
+
+	block, mac, iv, err := newHMACCipher(password, header)
+	if err != nil {
+		return err
+	}
+	cbc := cipher.NewCBCDecrypter(block, iv)
+
+	// Hold back the tag and the final block until the end of the input.
+	const holdBack = hmacTagSize + aes.BlockSize
+	br := bufio.NewReader(r)
+	chunk := make([]byte, bufferSize)
+	pending := make([]byte, 0, bufferSize+holdBack)
+
+	for {
+		n, readErr := io.ReadFull(br, chunk)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read encrypted data: %w", readErr)
+		}
+		pending = append(pending, chunk[:n]...)
+
+		if ready := (len(pending) - holdBack) / aes.BlockSize * aes.BlockSize; ready > 0 {
+			mac.Write(pending[:ready])
+			plain := make([]byte, ready)
+			cbc.CryptBlocks(plain, pending[:ready])
+			if _, err := w.Write(plain); err != nil {
+				return fmt.Errorf("failed to write decrypted block: %w", err)
+			}
+			pending = append(pending[:0], pending[ready:]...)
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	if len(pending) < holdBack || (len(pending)-hmacTagSize)%aes.BlockSize != 0 {
+		return ErrAuthFailed
+	}
+
+	ciphertext, tag := pending[:len(pending)-hmacTagSize], pending[len(pending)-hmacTagSize:]
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return ErrAuthFailed
+	}
+
+	plain := make([]byte, len(ciphertext))
+	cbc.CryptBlocks(plain, ciphertext)
+	final, err := removePKCS7Padding(plain, len(plain))
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(final); err != nil {
+		return fmt.Errorf("failed to write final block: %w", err)
+	}
+	return nil
+}
+
+// newHMACCipher derives the AES key, HMAC key and IV from password and the
+// header's salt, returning a MAC that has already absorbed the header.
+func newHMACCipher(password, header []byte) (cipher.Block, hash.Hash, []byte, error) {
+	encKey, macKey, iv, err := hmacKeys(password, header[len(hmacMagic)+1:])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(header)
+	return block, mac, iv, nil
+}
+
+// hmacKeys derives the AES key, HMAC key and IV of the encrypt-then-MAC
+// format from one PBKDF2 pass. The segments do not share capacity, so
+// appending to one cannot overwrite the next.
+func hmacKeys(password, salt []byte) (encKey, macKey, iv []byte, err error) {
+	keys, err := deriveKeysAndIV(password, salt, 2*aes256KeySize+aes.BlockSize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	encKey = keys[:aes256KeySize:aes256KeySize]
+	macKey = keys[aes256KeySize : 2*aes256KeySize : 2*aes256KeySize]
+	iv = keys[2*aes256KeySize:]
+	return encKey, macKey, iv, nil
+}
+
+// deriveKeysAndIV runs PBKDF2 once over password and salt and returns
+// totalLen bytes of key material for the caller to slice into keys and an
+// IV, so deriving several keys costs a single expensive pass.
+func deriveKeysAndIV(password, salt []byte, totalLen int) ([]byte, error) {
+	if totalLen <= 0 {
+		return nil, fmt.Errorf("invalid key material length %d", totalLen)
+	}
+	return pbkdf2.Key(password, salt, pbkdf2Iterations, totalLen, sha256.New), nil
+}
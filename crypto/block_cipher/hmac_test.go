@@ -0,0 +1,116 @@
+package block_cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestHMACRoundTrip(t *testing.T) {
+	password := []byte("s3cr3t")
+
+	for _, size := range []int{0, 1, aes.BlockSize, 100, bufferSize, bufferSize + 33} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		var encrypted bytes.Buffer
+		if err := EncryptStreamHMAC(&encrypted, bytes.NewReader(plain), password); err != nil {
+			t.Fatalf("size %d: encrypt error: %v", size, err)
+		}
+
+		var decrypted bytes.Buffer
+		if err := DecryptStreamHMAC(&decrypted, &encrypted, password); err != nil {
+			t.Fatalf("size %d: decrypt error: %v", size, err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plain) {
+			t.Errorf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestHMACDetectsTampering(t *testing.T) {
+	password := []byte("s3cr3t")
+	plain := bytes.Repeat([]byte("ledger entry "), 1000)
+
+	var encrypted bytes.Buffer
+	if err := EncryptStreamHMAC(&encrypted, bytes.NewReader(plain), password); err != nil {
+		t.Fatalf("encrypt error: %v", err)
+	}
+	data := encrypted.Bytes()
+	lastBlock := len(data) - hmacTagSize - aes.BlockSize
+
+	tests := []struct {
+		name     string
+		data     []byte
+		password []byte
+	}{
+		{"last block", flipByte(data, lastBlock+3), password},
+		{"first block", flipByte(data, hmacHeaderSize), password},
+		{"header", flipByte(data, len(hmacMagic)+2), password},
+		{"tag", flipByte(data, len(data)-1), password},
+		{"truncated", data[:len(data)-aes.BlockSize], password},
+		{"wrong password", data, []byte("wrong")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := DecryptStreamHMAC(&out, bytes.NewReader(tt.data), tt.password)
+			if !errors.Is(err, ErrAuthFailed) {
+				t.Fatalf("expected ErrAuthFailed, got %v", err)
+			}
+			// The final block is only released after verification.
+			if out.Len() >= len(plain) {
+				t.Errorf("final block was written despite failed verification (%d bytes)", out.Len())
+			}
+		})
+	}
+}
+
+func flipByte(data []byte, i int) []byte {
+	tampered := bytes.Clone(data)
+	tampered[i] ^= 0x01
+	return tampered
+}
+
+func TestHMACKeys(t *testing.T) {
+	password, salt := []byte("s3cr3t"), bytes.Repeat([]byte{0x42}, hmacSaltSize)
+
+	encKey, macKey, iv, err := hmacKeys(password, salt)
+	if err != nil {
+		t.Fatalf("hmacKeys error: %v", err)
+	}
+	if len(encKey) != aes256KeySize || len(macKey) != aes256KeySize || len(iv) != aes.BlockSize {
+		t.Fatalf("segment sizes = %d/%d/%d", len(encKey), len(macKey), len(iv))
+	}
+
+	encKey2, macKey2, iv2, _ := hmacKeys(password, salt)
+	if !bytes.Equal(encKey, encKey2) || !bytes.Equal(macKey, macKey2) || !bytes.Equal(iv, iv2) {
+		t.Error("derivation is not stable")
+	}
+
+	// The segments are consecutive slices of a single derivation.
+	material, err := deriveKeysAndIV(password, salt, 2*aes256KeySize+aes.BlockSize)
+	if err != nil {
+		t.Fatalf("deriveKeysAndIV error: %v", err)
+	}
+	if !bytes.Equal(material, slices.Concat(encKey, macKey, iv)) {
+		t.Error("segments do not tile the derived key material")
+	}
+	if bytes.Equal(encKey, macKey) {
+		t.Error("encryption and MAC keys must differ")
+	}
+
+	macBefore := bytes.Clone(macKey)
+	_ = append(encKey, 0xff)
+	if !bytes.Equal(macKey, macBefore) {
+		t.Error("appending to the encryption key overwrote the MAC key")
+	}
+
+	if _, err := deriveKeysAndIV(password, salt, 0); err == nil {
+		t.Error("expected error for zero length")
+	}
+}
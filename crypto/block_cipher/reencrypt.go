@@ -0,0 +1,56 @@
+package block_cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"fmt"
+	"io"
+)
+
+// Reencrypt decrypts r with oldPassword and encrypts the plaintext to w with
+// newPassword, streaming without holding the plaintext in memory. On error w
+// may hold partial output and should be discarded.
+func Reencrypt(w io.Writer, r io.Reader, oldPassword, newPassword []byte) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(DecryptStream(pw, r, oldPassword))
+	}()
+
+	err := EncryptStream(w, pr, newPassword)
+	pr.CloseWithError(err)
+	return err
+}
+
+// RotatePassword re-encrypts src from oldPassword to newPassword under a fresh
+// salt like Reencrypt, but writes nothing to dst unless the whole of src
+// decrypts: the new ciphertext is buffered in memory until decryption has
+// succeeded. A seekable src is first checked against the padding of its last
+// block, so a wrong password fails before anything is decrypted.
+//
+// The CBC format carries no MAC, so the padding is the only check of
+// oldPassword and a wrong password still passes about once in 256 tries,
+// yielding garbage encrypted under newPassword. Use the HMAC or GCM formats
+// where that matters.
+func RotatePassword(dst io.Writer, src io.Reader, oldPassword, newPassword []byte) error {
+	if rs, ok := src.(io.ReadSeeker); ok {
+		if _, err := NewDecryptingReader(rs, oldPassword); err != nil {
+			return err
+		}
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek encrypted data: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Reencrypt(&buf, src, oldPassword, newPassword); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(dst)
+	return err
+}
+
+// EncryptedSize returns the size of EncryptStream's output for a plaintext
+// of plainSize bytes: the header plus the PKCS#7-padded data.
+func EncryptedSize(plainSize int64) int64 {
+	return headerSize + (plainSize/aes.BlockSize+1)*aes.BlockSize
+}
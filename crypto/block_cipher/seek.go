@@ -0,0 +1,174 @@
+package block_cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxSeekReadSize bounds how much ciphertext a single DecryptingReader.Read
+// decrypts, so large reads do not allocate unbounded buffers.
+const maxSeekReadSize = 64 * 1024
+
+var (
+	ErrNegativeOffset = errors.New("seek to negative offset")
+	ErrOutOfRange     = errors.New("range is outside the plaintext")
+)
+
+// DecryptingReader decrypts data in the OpenSSL-compatible CBC format with
+// random access. CBC lets any block be decrypted from the ciphertext block
+// before it, so seeking only costs reading one extra block from the source.
+// The source must be seekable; use DecryptStream for plain io.Readers.
+type DecryptingReader struct {
+	src   io.ReadSeeker
+	block cipher.Block
+	iv    []byte
+	size  int64 // plaintext size
+	pos   int64
+}
+
+// NewDecryptingReader prepares random-access decryption of src, which must
+// hold data written by EncryptStream. It reads the header and the last block
+// to learn the plaintext size, returning ErrInvalidPadding for a wrong
+// password and ErrTruncated for a ciphertext that is not whole blocks.
+func NewDecryptingReader(src io.ReadSeeker, password []byte) (*DecryptingReader, error) {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek encrypted data: %w", err)
+	}
+	salt, err := readAndValidateHeader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seek encrypted data: %w", err)
+	}
+	cipherSize := end - headerSize
+	if cipherSize == 0 || cipherSize%aes.BlockSize != 0 {
+		return nil, ErrTruncated
+	}
+
+	key, iv := deriveKeyAndIV(password, salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	d := &DecryptingReader{src: src, block: block, iv: iv}
+
+	lastBlock := cipherSize/aes.BlockSize - 1
+	last, err := d.decryptBlocks(lastBlock, 1)
+	if err != nil {
+		return nil, err
+	}
+	unpadded, err := removePKCS7Padding(last, len(last))
+	if err != nil {
+		return nil, err
+	}
+	d.size = cipherSize - int64(aes.BlockSize-len(unpadded))
+	return d, nil
+}
+
+// DecryptRange writes exactly length plaintext bytes starting at offset of
+// the CBC-encrypted src to dst, decrypting only the blocks covering the range
+// plus the one before it. A range reaching past the end of the plaintext,
+// including any offset past EOF, returns ErrOutOfRange without writing.
+func DecryptRange(dst io.Writer, src io.ReadSeeker, password []byte, offset, length int64) error {
+	d, err := NewDecryptingReader(src, password)
+	if err != nil {
+		return err
+	}
+	if offset < 0 || length < 0 || offset > d.size || length > d.size-offset {
+		return fmt.Errorf("%w: %d bytes at offset %d of %d", ErrOutOfRange, length, offset, d.size)
+	}
+
+	if _, err := d.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.CopyN(dst, d, length)
+	return err
+}
+
+// Size returns the plaintext size.
+func (d *DecryptingReader) Size() int64 {
+	return d.size
+}
+
+// Read decrypts plaintext starting at the current offset.
+func (d *DecryptingReader) Read(p []byte) (int, error) {
+	if d.pos >= d.size {
+		return 0, io.EOF
+	}
+	if remaining := d.size - d.pos; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	if len(p) > maxSeekReadSize {
+		p = p[:maxSeekReadSize]
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	first := d.pos / aes.BlockSize
+	last := (d.pos + int64(len(p)) - 1) / aes.BlockSize
+	plain, err := d.decryptBlocks(first, last-first+1)
+	if err != nil {
+		return 0, err
+	}
+
+	n := copy(p, plain[d.pos-first*aes.BlockSize:])
+	d.pos += int64(n)
+	return n, nil
+}
+
+// Seek sets the plaintext offset for the next Read.
+func (d *DecryptingReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += d.pos
+	case io.SeekEnd:
+		offset += d.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, ErrNegativeOffset
+	}
+	d.pos = offset
+	return offset, nil
+}
+
+// decryptBlocks decrypts count ciphertext blocks starting at block index
+// first, using the preceding ciphertext block (or the derived IV) as IV.
+func (d *DecryptingReader) decryptBlocks(first, count int64) ([]byte, error) {
+	iv := d.iv
+	offset := headerSize + first*aes.BlockSize
+	if first > 0 {
+		offset -= aes.BlockSize
+	}
+	if _, err := d.src.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek encrypted data: %w", err)
+	}
+
+	buf := make([]byte, (count+1)*aes.BlockSize)
+	if first == 0 {
+		buf = buf[aes.BlockSize:]
+	}
+	if _, err := io.ReadFull(d.src, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrTruncated
+		}
+		return nil, fmt.Errorf("failed to read encrypted data: %w", err)
+	}
+	if first > 0 {
+		iv, buf = buf[:aes.BlockSize], buf[aes.BlockSize:]
+	}
+
+	plain := make([]byte, len(buf))
+	cipher.NewCBCDecrypter(d.block, iv).CryptBlocks(plain, buf)
+	return plain, nil
+}
@@ -0,0 +1,119 @@
+package block_cipher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestDecryptingReader(t *testing.T) {
+	password := []byte("seek password")
+
+	for _, size := range []int{0, 1, 15, 16, 17, 100, 4096, 70000} {
+		plain := make([]byte, size)
+		for i := range plain {
+			plain[i] = byte(i * 7)
+		}
+		var encrypted bytes.Buffer
+		if err := EncryptStream(&encrypted, bytes.NewReader(plain), password); err != nil {
+			t.Fatalf("EncryptStream error: %v", err)
+		}
+
+		d, err := NewDecryptingReader(bytes.NewReader(encrypted.Bytes()), password)
+		if err != nil {
+			t.Fatalf("size %d: NewDecryptingReader error: %v", size, err)
+		}
+		if d.Size() != int64(size) {
+			t.Errorf("size %d: Size() = %d", size, d.Size())
+		}
+
+		got, err := io.ReadAll(d)
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Errorf("size %d: full read mismatch (err %v)", size, err)
+		}
+
+		for _, off := range []int{0, 5, 16, 33, size / 2, size - 1} {
+			if off < 0 || off >= size {
+				continue
+			}
+			if _, err := d.Seek(int64(off), io.SeekStart); err != nil {
+				t.Fatalf("Seek error: %v", err)
+			}
+			buf := make([]byte, 20)
+			n, _ := io.ReadFull(d, buf)
+			end := min(off+20, size)
+			if !bytes.Equal(buf[:n], plain[off:end]) {
+				t.Errorf("size %d offset %d: got %x, want %x", size, off, buf[:n], plain[off:end])
+			}
+		}
+	}
+}
+
+func TestDecryptingReaderErrors(t *testing.T) {
+	var encrypted bytes.Buffer
+	EncryptStream(&encrypted, bytes.NewReader([]byte("short secret")), []byte("right"))
+
+	if _, err := NewDecryptingReader(bytes.NewReader(encrypted.Bytes()[:encrypted.Len()-3]), []byte("right")); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+
+	d, err := NewDecryptingReader(bytes.NewReader(encrypted.Bytes()), []byte("right"))
+	if err != nil {
+		t.Fatalf("NewDecryptingReader error: %v", err)
+	}
+	if _, err := d.Seek(-1, io.SeekStart); !errors.Is(err, ErrNegativeOffset) {
+		t.Errorf("expected ErrNegativeOffset, got %v", err)
+	}
+}
+
+func TestDecryptRange(t *testing.T) {
+	password := []byte("range password")
+	plain := make([]byte, 100000)
+	for i := range plain {
+		plain[i] = byte(i % 251)
+	}
+
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader(plain), password); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+	var full bytes.Buffer
+	if err := DecryptStream(&full, bytes.NewReader(encrypted.Bytes()), password); err != nil {
+		t.Fatalf("DecryptStream error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		wantErr        error
+	}{
+		{"start", 0, 10, nil},
+		{"middle", 40007, 70000 - 40007, nil},
+		{"block aligned", 16 * 100, 16 * 3, nil},
+		{"to the end", int64(len(plain)) - 5, 5, nil},
+		{"empty at end", int64(len(plain)), 0, nil},
+		{"past end", int64(len(plain)) + 1, 1, ErrOutOfRange},
+		{"overlapping end", int64(len(plain)) - 5, 6, ErrOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := DecryptRange(&out, bytes.NewReader(encrypted.Bytes()), password, tt.offset, tt.length)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || out.Len() != 0 {
+					t.Fatalf("expected %v and no output, got %v with %d bytes", tt.wantErr, err, out.Len())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecryptRange error: %v", err)
+			}
+			want := full.Bytes()[tt.offset : tt.offset+tt.length]
+			if !bytes.Equal(out.Bytes(), want) {
+				t.Errorf("range differs from full decryption (%d bytes, want %d)", out.Len(), len(want))
+			}
+		})
+	}
+}
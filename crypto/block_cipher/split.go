@@ -0,0 +1,45 @@
+package block_cipher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidPartSize = errors.New("part size must be positive")
+
+// SplitEncrypted copies the encrypted stream r into consecutive parts of
+// partSize bytes, the last one possibly shorter, for multipart uploads.
+// newPart is called with indexes counting from 0 only once there is data for
+// the part, and every part is closed before the next is opened. Concatenating
+// the parts in index order restores r exactly, so they decrypt with the
+// function matching the format r was written in. To encrypt and split in one
+// pass, feed r from EncryptStream through an io.Pipe.
+func SplitEncrypted(r io.Reader, partSize int64, newPart func(index int) (io.WriteCloser, error)) error {
+	if partSize <= 0 {
+		return ErrInvalidPartSize
+	}
+
+	br := bufio.NewReaderSize(r, bufferSize)
+	for index := 0; ; index++ {
+		if _, err := br.Peek(1); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read encrypted data: %w", err)
+		}
+
+		part, err := newPart(index)
+		if err != nil {
+			return fmt.Errorf("failed to create part %d: %w", index, err)
+		}
+		_, err = io.CopyN(part, br, partSize)
+		closeErr := part.Close()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to write part %d: %w", index, err)
+		}
+		if closeErr != nil {
+			return fmt.Errorf("failed to close part %d: %w", index, closeErr)
+		}
+	}
+}
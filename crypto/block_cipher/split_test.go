@@ -0,0 +1,73 @@
+package block_cipher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// testPart is an in-memory part that records whether it was closed.
+type testPart struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (p *testPart) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestSplitEncrypted(t *testing.T) {
+	password := []byte("multipart")
+	plain := bytes.Repeat([]byte("upload me "), 10000)
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(EncryptStream(pw, bytes.NewReader(plain), password))
+	}()
+
+	// Two full parts and a shorter third one.
+	partSize := EncryptedSize(int64(len(plain)))/3 + 1
+	var parts []*testPart
+	err := SplitEncrypted(pr, partSize, func(index int) (io.WriteCloser, error) {
+		if index != len(parts) {
+			t.Errorf("newPart index = %d, want %d", index, len(parts))
+		}
+		if len(parts) > 0 && !parts[len(parts)-1].closed {
+			t.Errorf("part %d opened before part %d was closed", index, index-1)
+		}
+		p := &testPart{}
+		parts = append(parts, p)
+		return p, nil
+	})
+	if err != nil {
+		t.Fatalf("SplitEncrypted error: %v", err)
+	}
+
+	if len(parts) != 3 {
+		t.Fatalf("got %d parts, want 3", len(parts))
+	}
+	var joined bytes.Buffer
+	for i, p := range parts {
+		if !p.closed {
+			t.Errorf("part %d was not closed", i)
+		}
+		if i < len(parts)-1 && int64(p.Len()) != partSize {
+			t.Errorf("part %d has %d bytes, want %d", i, p.Len(), partSize)
+		}
+		joined.Write(p.Bytes())
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptStream(&decrypted, &joined, password); err != nil {
+		t.Fatalf("DecryptStream error: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plain) {
+		t.Error("joined parts do not decrypt to the original")
+	}
+
+	if err := SplitEncrypted(bytes.NewReader(nil), 0, nil); !errors.Is(err, ErrInvalidPartSize) {
+		t.Errorf("expected ErrInvalidPartSize, got %v", err)
+	}
+}
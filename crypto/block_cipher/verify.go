@@ -0,0 +1,58 @@
+package block_cipher
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DecryptStreamAuto decrypts r into w, choosing the format from its header:
+// the OpenSSL-compatible format, the authenticated GCM format (with no AAD)
+// or the HMAC format.
+func DecryptStreamAuto(w io.Writer, r io.Reader, password []byte) error {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(gcmMagic))
+	switch string(magic) {
+	case gcmMagic:
+		return DecryptStreamGCM(w, br, password, nil)
+	case hmacMagic:
+		return DecryptStreamHMAC(w, br, password)
+	}
+	return DecryptStream(w, br, password)
+}
+
+// Verify checks that r decrypts with password, without producing any output.
+// The format is detected like DecryptStreamAuto. It returns nil on success,
+// or the error decryption would report, e.g. ErrInvalidPadding for a wrong
+// password, ErrTruncated or ErrAuthFailed.
+func Verify(r io.Reader, password []byte) error {
+	return DecryptStreamAuto(io.Discard, r, password)
+}
+
+// IsEncrypted reports whether r starts with the "Salted__" magic written by
+// EncryptStream and OpenSSL. It reads at most 8 bytes. If r is an io.Seeker it
+// is rewound to where it was; otherwise those bytes are consumed, so wrap r in
+// a bufio.Reader and pass that if the data must be read again. Inputs shorter
+// than the magic are reported as not encrypted rather than as an error.
+func IsEncrypted(r io.Reader) (bool, error) {
+	magic := make([]byte, len(magicHeader))
+	if br, ok := r.(*bufio.Reader); ok {
+		peeked, err := br.Peek(len(magic))
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		return string(peeked) == magicHeader, nil
+	}
+
+	n, err := io.ReadFull(r, magic)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+
+	if s, ok := r.(io.Seeker); ok && n > 0 {
+		if _, err := s.Seek(int64(-n), io.SeekCurrent); err != nil {
+			return false, fmt.Errorf("failed to rewind input: %w", err)
+		}
+	}
+	return n == len(magic) && string(magic) == magicHeader, nil
+}
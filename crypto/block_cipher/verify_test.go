@@ -0,0 +1,225 @@
+package block_cipher
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestVerify(t *testing.T) {
+	password := []byte("s3cr3t")
+	plain := bytes.Repeat([]byte("backup data "), 1000)
+
+	// A fixed salt keeps the wrong-password padding check deterministic.
+	defer func() { randReader = rand.Reader }()
+	randReader = bytes.NewReader(bytes.Repeat([]byte{0x42}, saltSize))
+
+	var cbc, gcm, mac bytes.Buffer
+	if err := EncryptStream(&cbc, bytes.NewReader(plain), password); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+	randReader = rand.Reader
+	if err := EncryptStreamGCM(&gcm, bytes.NewReader(plain), password, nil); err != nil {
+		t.Fatalf("EncryptStreamGCM error: %v", err)
+	}
+	if err := EncryptStreamHMAC(&mac, bytes.NewReader(plain), password); err != nil {
+		t.Fatalf("EncryptStreamHMAC error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		password []byte
+		wantErr  error
+	}{
+		{"good file", cbc.Bytes(), password, nil},
+		{"good gcm file", gcm.Bytes(), password, nil},
+		{"good hmac file", mac.Bytes(), password, nil},
+		{"wrong password hmac", mac.Bytes(), []byte("wrong"), ErrAuthFailed},
+		{"wrong password", cbc.Bytes(), []byte("wrong"), ErrInvalidPadding},
+		{"wrong password gcm", gcm.Bytes(), []byte("wrong"), ErrAuthFailed},
+		{"truncated", cbc.Bytes()[:cbc.Len()-5], password, ErrTruncated},
+		{"truncated at block boundary", cbc.Bytes()[:cbc.Len()-16], password, ErrInvalidPadding},
+		{"header only", cbc.Bytes()[:headerSize], password, ErrTruncated},
+		{"truncated gcm", gcm.Bytes()[:gcm.Len()-5], password, ErrAuthFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(bytes.NewReader(tt.data), tt.password)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Verify error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	plain := bytes.Repeat([]byte("rotate me "), 5000)
+
+	// A fixed salt keeps the wrong-password padding check deterministic.
+	defer func() { randReader = rand.Reader }()
+	randReader = bytes.NewReader(bytes.Repeat([]byte{0x42}, saltSize))
+
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader(plain), []byte("old")); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	randReader = rand.Reader
+	var reencrypted bytes.Buffer
+	if err := Reencrypt(&reencrypted, bytes.NewReader(encrypted.Bytes()), []byte("old"), []byte("new")); err != nil {
+		t.Fatalf("Reencrypt error: %v", err)
+	}
+	if got, want := int64(reencrypted.Len()), EncryptedSize(int64(len(plain))); got != want {
+		t.Errorf("output size = %d, EncryptedSize = %d", got, want)
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptStream(&decrypted, &reencrypted, []byte("new")); err != nil {
+		t.Fatalf("DecryptStream error: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plain) {
+		t.Error("reencrypted data does not decrypt to the original")
+	}
+
+	err := Reencrypt(&bytes.Buffer{}, bytes.NewReader(encrypted.Bytes()), []byte("wrong"), []byte("new"))
+	if err == nil {
+		t.Error("expected error for wrong old password")
+	}
+}
+
+func TestRotatePassword(t *testing.T) {
+	plain := bytes.Repeat([]byte("rotate me "), 5000)
+
+	// Fixed salts keep the wrong-password padding checks deterministic.
+	defer func() { randReader = rand.Reader }()
+	randReader = bytes.NewReader(bytes.Repeat([]byte{0x42}, saltSize))
+
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader(plain), []byte("old")); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	sources := map[string]func() io.Reader{
+		"seekable": func() io.Reader { return bytes.NewReader(encrypted.Bytes()) },
+		"stream":   func() io.Reader { return struct{ io.Reader }{bytes.NewReader(encrypted.Bytes())} },
+	}
+	for name, src := range sources {
+		t.Run(name, func(t *testing.T) {
+			randReader = bytes.NewReader(bytes.Repeat([]byte{0x24}, saltSize))
+			var rotated bytes.Buffer
+			if err := RotatePassword(&rotated, src(), []byte("old"), []byte("new")); err != nil {
+				t.Fatalf("RotatePassword error: %v", err)
+			}
+			if bytes.Equal(rotated.Bytes()[:headerSize], encrypted.Bytes()[:headerSize]) {
+				t.Error("expected a fresh salt")
+			}
+
+			var decrypted bytes.Buffer
+			if err := DecryptStream(&decrypted, bytes.NewReader(rotated.Bytes()), []byte("new")); err != nil {
+				t.Fatalf("DecryptStream with new password error: %v", err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), plain) {
+				t.Error("rotated data does not decrypt to the original")
+			}
+			if err := DecryptStream(io.Discard, bytes.NewReader(rotated.Bytes()), []byte("old")); err == nil {
+				t.Error("expected the old password to fail after rotation")
+			}
+
+			randReader = rand.Reader
+			var out bytes.Buffer
+			if err := RotatePassword(&out, src(), []byte("wrong"), []byte("new")); !errors.Is(err, ErrInvalidPadding) {
+				t.Errorf("expected ErrInvalidPadding for wrong old password, got %v", err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("wrote %d bytes despite the wrong old password", out.Len())
+			}
+		})
+	}
+}
+
+// failingSeeker fails reads once more than limit bytes have been read, after
+// RotatePassword's padding check has passed.
+type failingSeeker struct {
+	*bytes.Reader
+	read, limit int
+}
+
+func (f *failingSeeker) Read(p []byte) (int, error) {
+	if f.read >= f.limit {
+		return 0, errors.New("disk read failed")
+	}
+	n, err := f.Reader.Read(p)
+	f.read += n
+	return n, err
+}
+
+func TestRotatePasswordReadError(t *testing.T) {
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader(bytes.Repeat([]byte("rotate me "), 5000)), []byte("old")); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	src := &failingSeeker{Reader: bytes.NewReader(encrypted.Bytes()), limit: encrypted.Len() / 2}
+	var out bytes.Buffer
+	if err := RotatePassword(&out, src, []byte("old"), []byte("new")); err == nil {
+		t.Fatal("expected the read error")
+	}
+	if out.Len() != 0 {
+		t.Errorf("wrote %d bytes of a failed rotation", out.Len())
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader([]byte("classified")), []byte("pw")); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  bool
+	}{
+		{"encrypted", encrypted.Bytes(), true},
+		{"plain", []byte("just a plain text file"), false},
+		{"three bytes", []byte("Sal"), false},
+		{"empty", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(tt.input)
+			got, err := IsEncrypted(r)
+			if err != nil || got != tt.want {
+				t.Fatalf("IsEncrypted = %v, %v; want %v, nil", got, err, tt.want)
+			}
+			if rest, _ := io.ReadAll(r); !bytes.Equal(rest, tt.input) {
+				t.Error("seekable input was not rewound")
+			}
+
+			br := bufio.NewReader(bytes.NewReader(tt.input))
+			if got, err := IsEncrypted(br); err != nil || got != tt.want {
+				t.Errorf("bufio: IsEncrypted = %v, %v; want %v, nil", got, err, tt.want)
+			}
+			if rest, _ := io.ReadAll(br); !bytes.Equal(rest, tt.input) {
+				t.Error("bufio input was consumed")
+			}
+		})
+	}
+
+	if _, err := IsEncrypted(iotest.ErrReader(errors.New("disk error"))); err == nil {
+		t.Error("expected read errors to be returned")
+	}
+}
@@ -0,0 +1,154 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNotFound    = errors.New("secret not found in keyring")
+	ErrInvalidRef  = errors.New("keyring reference must be <service>/<user>")
+	ErrUnsupported = errors.New("keyring is not supported on this platform")
+)
+
+// Provider stores secrets by service and user.
+type Provider interface {
+	Get(service, user string) ([]byte, error)
+	Set(service, user string, secret []byte) error
+}
+
+// ParseRef splits a "<service>/<user>" reference.
+func ParseRef(ref string) (service, user string, err error) {
+	service, user, ok := strings.Cut(ref, "/")
+	if !ok || service == "" || user == "" {
+		return "", "", ErrInvalidRef
+	}
+	return service, user, nil
+}
+
+// MemoryProvider keeps secrets in process memory, for tests and for
+// environments without an OS keyring.
+type MemoryProvider struct {
+	mu      sync.Mutex
+	secrets map[string][]byte
+}
+
+// NewMemoryProvider creates an empty in-memory provider.
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{secrets: make(map[string][]byte)}
+}
+
+// Get implements Provider.
+func (p *MemoryProvider) Get(service, user string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	secret, ok := p.secrets[service+"/"+user]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bytes.Clone(secret), nil
+}
+
+// Set implements Provider.
+func (p *MemoryProvider) Set(service, user string, secret []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.secrets[service+"/"+user] = bytes.Clone(secret)
+	return nil
+}
+
+// SystemProvider uses the OS keyring through its command line tool:
+// security(1) on macOS and secret-tool(1) (libsecret) on Linux. Secrets are
+// passed on stdin, never as arguments, so they do not show up in the process
+// list. The zero value is ready to use.
+type SystemProvider struct {
+	goos string                              // replaces runtime.GOOS in tests
+	run  func(cmd *exec.Cmd) ([]byte, error) // replaces cmd.Output in tests
+}
+
+// platform returns the OS whose keyring tool is used.
+func (p SystemProvider) platform() string {
+	if p.goos != "" {
+		return p.goos
+	}
+	return runtime.GOOS
+}
+
+// output runs cmd and returns its stdout.
+func (p SystemProvider) output(cmd *exec.Cmd) ([]byte, error) {
+	if p.run != nil {
+		return p.run(cmd)
+	}
+	return cmd.Output()
+}
+
+// Get implements Provider.
+func (p SystemProvider) Get(service, user string) ([]byte, error) {
+	var cmd *exec.Cmd
+	switch p.platform() {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", user, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "user", user)
+	default:
+		return nil, ErrUnsupported
+	}
+
+	out, err := p.output(cmd)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("reading keyring: %w", err)
+	}
+
+	secret := bytes.TrimSuffix(out, []byte("\n"))
+	if len(secret) == 0 {
+		return nil, ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set implements Provider.
+func (p SystemProvider) Set(service, user string, secret []byte) error {
+	var cmd *exec.Cmd
+	switch p.platform() {
+	case "darwin":
+		// security -i reads commands from stdin; -X takes the secret in hex
+		// so it needs no quoting.
+		if strings.ContainsAny(service+user, "\r\n") {
+			return ErrInvalidRef
+		}
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader("add-generic-password -U -s " + quoteArg(service) +
+			" -a " + quoteArg(user) + " -X " + hex.EncodeToString(secret) + "\n")
+	case "linux":
+		cmd = exec.Command("secret-tool", "store", "--label", service+"/"+user, "service", service, "user", user)
+		cmd.Stdin = bytes.NewReader(secret)
+	default:
+		return ErrUnsupported
+	}
+
+	if _, err := p.output(cmd); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("writing keyring: %w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+		}
+		return fmt.Errorf("writing keyring: %w", err)
+	}
+	return nil
+}
+
+// quoteArg single-quotes s for the command line of security -i.
+func quoteArg(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
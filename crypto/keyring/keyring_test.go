@@ -0,0 +1,148 @@
+package keyring
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMemoryProvider(t *testing.T) {
+	p := NewMemoryProvider()
+
+	if _, err := p.Get("rum", "backup"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := p.Set("rum", "backup", []byte("s3cr3t")); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+
+	secret, err := p.Get("rum", "backup")
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if string(secret) != "s3cr3t" {
+		t.Errorf("secret = %q, want %q", secret, "s3cr3t")
+	}
+}
+
+func TestSystemProviderSet(t *testing.T) {
+	tests := []struct {
+		goos      string
+		wantArgs  []string
+		wantStdin string
+	}{
+		{
+			goos:      "darwin",
+			wantArgs:  []string{"security", "-i"},
+			wantStdin: "add-generic-password -U -s 'rum' -a 'o'\"'\"'brien' -X 733363723374\n",
+		},
+		{
+			goos:      "linux",
+			wantArgs:  []string{"secret-tool", "store", "--label", "rum/o'brien", "service", "rum", "user", "o'brien"},
+			wantStdin: "s3cr3t",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			var ran *exec.Cmd
+			p := SystemProvider{goos: tt.goos, run: func(cmd *exec.Cmd) ([]byte, error) {
+				ran = cmd
+				return nil, nil
+			}}
+
+			if err := p.Set("rum", "o'brien", []byte("s3cr3t")); err != nil {
+				t.Fatalf("Set error: %v", err)
+			}
+			if !reflect.DeepEqual(ran.Args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", ran.Args, tt.wantArgs)
+			}
+			for _, arg := range ran.Args {
+				if strings.Contains(arg, "s3cr3t") {
+					t.Errorf("secret passed as argument %q", arg)
+				}
+			}
+			stdin, _ := io.ReadAll(ran.Stdin)
+			if string(stdin) != tt.wantStdin {
+				t.Errorf("stdin = %q, want %q", stdin, tt.wantStdin)
+			}
+		})
+	}
+
+	if err := (SystemProvider{goos: "plan9"}).Set("rum", "backup", nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+	if err := (SystemProvider{goos: "darwin"}).Set("rum", "back\nup", nil); !errors.Is(err, ErrInvalidRef) {
+		t.Errorf("expected ErrInvalidRef for a newline in the user, got %v", err)
+	}
+}
+
+func TestSystemProviderGet(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		out      string
+		err      error
+		wantArgs []string
+		want     string
+		wantErr  error
+	}{
+		{"darwin", "darwin", "s3cr3t\n", nil, []string{"security", "find-generic-password", "-s", "rum", "-a", "backup", "-w"}, "s3cr3t", nil},
+		{"linux", "linux", "s3cr3t", nil, []string{"secret-tool", "lookup", "service", "rum", "user", "backup"}, "s3cr3t", nil},
+		{"missing entry", "linux", "", &exec.ExitError{}, nil, "", ErrNotFound},
+		{"empty secret", "linux", "\n", nil, nil, "", ErrNotFound},
+		{"unsupported", "windows", "", nil, nil, "", ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran *exec.Cmd
+			p := SystemProvider{goos: tt.goos, run: func(cmd *exec.Cmd) ([]byte, error) {
+				ran = cmd
+				return []byte(tt.out), tt.err
+			}}
+
+			secret, err := p.Get("rum", "backup")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get error = %v, want %v", err, tt.wantErr)
+			}
+			if string(secret) != tt.want {
+				t.Errorf("secret = %q, want %q", secret, tt.want)
+			}
+			if tt.wantArgs != nil && !reflect.DeepEqual(ran.Args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", ran.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		ref         string
+		wantService string
+		wantUser    string
+		wantErr     bool
+	}{
+		{"rum/backup", "rum", "backup", false},
+		{"rum/team/backup", "rum", "team/backup", false},
+		{"rum", "", "", true},
+		{"/backup", "", "", true},
+		{"rum/", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			service, user, err := ParseRef(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRef(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if service != tt.wantService || user != tt.wantUser {
+				t.Errorf("ParseRef(%q) = %q, %q", tt.ref, service, user)
+			}
+		})
+	}
+}
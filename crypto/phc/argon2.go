@@ -2,10 +2,10 @@ package phc
 
 import (
 	"crypto/subtle"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -13,8 +13,26 @@ import (
 var (
 	ErrInvalidHash         = errors.New("the encoded hash is not in the correct format")
 	ErrIncompatibleVersion = errors.New("incompatible version of argon2")
+	ErrWeakConfig          = errors.New("argon2 configuration is too weak")
+	ErrSaltTooShort        = errors.New("salt must be at least 8 bytes")
+	ErrInvalidConcurrency  = errors.New("concurrency must be at least 1")
+	ErrInvalidConfig       = errors.New("invalid argon2 configuration")
 )
 
+// minSaltLength is the shortest salt accepted by GenerateWithSalt.
+const minSaltLength = 8
+
+// Key length bounds accepted by NewArgon2Config.
+const (
+	minKeyLength = 16
+	maxKeyLength = 1024
+)
+
+// maxMemory caps the argon2 memory parameter, in KiB, that configurations
+// and parsed hashes may ask for, so a stored hash cannot make verification
+// allocate without bound.
+const maxMemory = 4 * 1024 * 1024 // 4 GiB
+
 type Argon2Config struct {
 	memory      uint32
 	iterations  uint32
@@ -41,6 +59,35 @@ func GetDefaultArgon2Config() *Argon2Config {
 	}
 }
 
+// NewArgon2Config builds a custom configuration for NewArgon2PHC. memory is
+// in KiB and must be at least 8 KiB per lane of parallelism and at most
+// 4 GiB; iterations and
+// parallelism must be at least 1, saltLength at least 8 bytes and keyLength
+// between 16 and 1024 bytes. Other values return ErrInvalidConfig.
+func NewArgon2Config(memory, iterations uint32, parallelism uint8, saltLength, keyLength uint32) (*Argon2Config, error) {
+	switch {
+	case iterations < 1:
+		return nil, fmt.Errorf("%w: iterations must be at least 1", ErrInvalidConfig)
+	case parallelism < 1:
+		return nil, fmt.Errorf("%w: parallelism must be at least 1", ErrInvalidConfig)
+	case memory < 8*uint32(parallelism):
+		return nil, fmt.Errorf("%w: memory must be at least %d KiB for parallelism %d", ErrInvalidConfig, 8*uint32(parallelism), parallelism)
+	case memory > maxMemory:
+		return nil, fmt.Errorf("%w: memory must be at most %d KiB", ErrInvalidConfig, maxMemory)
+	case saltLength < minSaltLength:
+		return nil, fmt.Errorf("%w: salt length must be at least %d bytes", ErrInvalidConfig, minSaltLength)
+	case keyLength < minKeyLength || keyLength > maxKeyLength:
+		return nil, fmt.Errorf("%w: key length must be between %d and %d bytes", ErrInvalidConfig, minKeyLength, maxKeyLength)
+	}
+	return &Argon2Config{
+		memory:      memory,
+		iterations:  iterations,
+		parallelism: parallelism,
+		saltLength:  saltLength,
+		keyLength:   keyLength,
+	}, nil
+}
+
 func newArgon2PHCDefault() *argon2Pch {
 	return NewArgon2PHC(GetDefaultArgon2Config())
 }
@@ -61,74 +108,155 @@ func (a *argon2Pch) GenerateFromBytes(secret []byte) (encodedHash string, err er
 		return "", err
 	}
 
-	hash := argon2.IDKey(secret, salt, a.iterations, a.memory, a.parallelism, a.keyLength)
+	return a.GenerateWithSalt(secret, salt)
+}
 
-	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
-	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
+// GenerateWithSalt hashes secret with a caller-supplied salt, for protocols
+// that store or derive the salt separately. The same secret and salt always
+// produce the same hash. Salts shorter than 8 bytes are rejected.
+func (a *argon2Pch) GenerateWithSalt(secret, salt []byte) (encodedHash string, err error) {
+	if len(salt) < minSaltLength {
+		return "", ErrSaltTooShort
+	}
 
-	encodedHash = fmt.Sprintf(
-		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
-		argon2.Version, a.memory, a.iterations, a.parallelism, b64Salt, b64Hash,
-	)
+	hash := argon2.IDKey(secret, salt, a.iterations, a.memory, a.parallelism, a.keyLength)
 
-	return encodedHash, nil
+	return EncodePHC(a.params(), salt, hash)
+}
+
+// params returns the PHC parameters for the backend's configuration.
+func (a *argon2Pch) params() PHCParams {
+	return PHCParams{
+		Algorithm:   Argon2Id,
+		Version:     argon2.Version,
+		Memory:      a.memory,
+		Iterations:  a.iterations,
+		Parallelism: a.parallelism,
+	}
 }
 
 func (a *argon2Pch) GenerateFromString(password string) (encodedHash string, err error) {
 	return a.GenerateFromBytes([]byte(password))
 }
 
-func (a *argon2Pch) decodeHash(encodedHash string) (cfg *Argon2Config, salt, hash []byte, err error) {
-	vals := strings.Split(encodedHash, "$")
-	if len(vals) != 6 {
-		return nil, nil, nil, ErrInvalidHash
+// GenerateBatch hashes every secret, running at most concurrency hashes at a
+// time since each one holds the configured memory (64 MiB by default) while
+// it runs. Results are in the order of secrets. If any hash fails, the error
+// of the first failing secret is returned.
+func (a *argon2Pch) GenerateBatch(secrets [][]byte, concurrency int) ([]string, error) {
+	if concurrency < 1 {
+		return nil, ErrInvalidConcurrency
 	}
 
-	var version int
-	_, err = fmt.Sscanf(vals[2], "v=%d", &version)
-	if err != nil {
-		return nil, nil, nil, err
-	}
+	hashes := make([]string, len(secrets))
+	errs := make([]error, len(secrets))
+	jobs := make(chan int)
 
-	if version != argon2.Version {
-		return nil, nil, nil, ErrIncompatibleVersion
+	var wg sync.WaitGroup
+	for range min(concurrency, len(secrets)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hashes[i], errs[i] = a.GenerateFromBytes(secrets[i])
+			}
+		}()
 	}
-
-	p := Argon2Config{}
-	_, err = fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism)
-	if err != nil {
-		return nil, nil, nil, err
+	for i := range secrets {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
 
-	salt, err = base64.RawStdEncoding.Strict().DecodeString(vals[4])
-	if err != nil {
-		return nil, nil, nil, err
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("hashing secret %d: %w", i, err)
+		}
 	}
-	p.saltLength = uint32(len(salt))
+	return hashes, nil
+}
 
-	hash, err = base64.RawStdEncoding.Strict().DecodeString(vals[5])
+func (a *argon2Pch) decodeHash(encodedHash string) (cfg *Argon2Config, salt, hash []byte, err error) {
+	params, salt, hash, err := ParsePHC(encodedHash)
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	p.keyLength = uint32(len(hash))
 
+	p := Argon2Config{
+		memory:      params.Memory,
+		iterations:  params.Iterations,
+		parallelism: params.Parallelism,
+		saltLength:  uint32(len(salt)),
+		keyLength:   uint32(len(hash)),
+	}
 	return &p, salt, hash, nil
 }
 
+// Mismatch reasons reported by CheckSecretDetailed.
+const (
+	ReasonMalformedHash    = "malformed hash"
+	ReasonVersionMismatch  = "version mismatch"
+	ReasonPasswordMismatch = "password mismatch"
+)
+
 func (a *argon2Pch) CheckSecret(encodedHash string, password []byte) (match bool, err error) {
+	match, _, err = a.CheckSecretDetailed(encodedHash, password)
+	return match, err
+}
+
+// CheckSecretDetailed is CheckSecret for debugging: on failure, reason tells
+// whether the hash was malformed, used another argon2 version, or the secret
+// was simply wrong. The secret comparison is constant time as in CheckSecret;
+// the other reasons depend only on the hash. Reasons are meant for logs, not
+// for end users.
+func (a *argon2Pch) CheckSecretDetailed(encodedHash string, secret []byte) (match bool, reason string, err error) {
 	p, salt, hash, err := a.decodeHash(encodedHash)
+	if errors.Is(err, ErrIncompatibleVersion) {
+		return false, ReasonVersionMismatch, err
+	}
 	if err != nil {
-		return false, err
+		return false, ReasonMalformedHash, err
 	}
 
-	otherHash := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+	otherHash := argon2.IDKey(secret, salt, p.iterations, p.memory, p.parallelism, p.keyLength)
 	if subtle.ConstantTimeCompare(hash, otherHash) == 1 {
-		return true, nil
+		return true, "", nil
 	}
 
-	return false, nil
+	return false, ReasonPasswordMismatch, nil
 }
 
 func (a *argon2Pch) CheckPassword(encodedHash, password string) (match bool, err error) {
 	return a.CheckSecret(encodedHash, []byte(password))
 }
+
+// NeedsRehash reports whether encodedHash is malformed or uses parameters,
+// salt or key length different from the current configuration.
+func (a *argon2Pch) NeedsRehash(encodedHash string) bool {
+	p, _, _, err := a.decodeHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return p.memory != a.memory ||
+		p.iterations != a.iterations ||
+		p.parallelism != a.parallelism ||
+		p.saltLength != a.saltLength ||
+		p.keyLength != a.keyLength
+}
+
+// EstimateCost measures how long a single hash takes with the current config.
+func (a *argon2Pch) EstimateCost() time.Duration {
+	salt := make([]byte, a.saltLength)
+	start := time.Now()
+	argon2.IDKey([]byte("rum-cost-estimate"), salt, a.iterations, a.memory, a.parallelism, a.keyLength)
+	return time.Since(start)
+}
+
+// WarnIfWeak returns ErrWeakConfig if hashing takes less than threshold,
+// letting startup code refuse to run with an accidentally cheap config.
+func (a *argon2Pch) WarnIfWeak(threshold time.Duration) error {
+	if cost := a.EstimateCost(); cost < threshold {
+		return fmt.Errorf("%w: hashing took %s, expected at least %s", ErrWeakConfig, cost, threshold)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package phc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWarnIfWeak(t *testing.T) {
+	weak := NewArgon2PHC(&Argon2Config{memory: 8, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32})
+
+	if cost := weak.EstimateCost(); cost <= 0 {
+		t.Errorf("EstimateCost() = %s, want a positive duration", cost)
+	}
+
+	if err := weak.WarnIfWeak(time.Hour); !errors.Is(err, ErrWeakConfig) {
+		t.Errorf("expected ErrWeakConfig, got %v", err)
+	}
+	if err := weak.WarnIfWeak(0); err != nil {
+		t.Errorf("expected no warning for a zero threshold, got %v", err)
+	}
+}
+
+func TestCheckSecretRejectsEmptySegments(t *testing.T) {
+	a := newArgon2PHCDefault()
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty salt", "$argon2id$v=19$m=65536,t=3,p=2$$aGFzaGhhc2hoYXNoaGFzaA"},
+		{"empty hash", "$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHRzYWx0c2FsdA$"},
+		{"empty salt and hash", "$argon2id$v=19$m=65536,t=3,p=2$$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := a.CheckSecret(tt.hash, []byte("password"))
+			if !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("expected ErrInvalidHash, got %v", err)
+			}
+			if match {
+				t.Error("expected no match")
+			}
+		})
+	}
+}
+
+func TestGenerateWithSalt(t *testing.T) {
+	a := NewArgon2PHC(&Argon2Config{memory: 8, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32})
+	secret := []byte("correct horse")
+
+	first, err := a.GenerateWithSalt(secret, []byte("per-user-salt-01"))
+	if err != nil {
+		t.Fatalf("GenerateWithSalt error: %v", err)
+	}
+	again, _ := a.GenerateWithSalt(secret, []byte("per-user-salt-01"))
+	if first != again {
+		t.Errorf("same secret and salt gave %q and %q", first, again)
+	}
+
+	other, _ := a.GenerateWithSalt(secret, []byte("per-user-salt-02"))
+	if other == first {
+		t.Error("different salts produced the same hash")
+	}
+
+	if match, err := a.CheckSecret(first, secret); err != nil || !match {
+		t.Errorf("CheckSecret = %v, %v; want true, nil", match, err)
+	}
+
+	if _, err := a.GenerateWithSalt(secret, []byte("short")); !errors.Is(err, ErrSaltTooShort) {
+		t.Errorf("expected ErrSaltTooShort, got %v", err)
+	}
+}
+
+func TestCheckSecretDetailed(t *testing.T) {
+	a := NewArgon2PHC(&Argon2Config{memory: 8, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32})
+	hash, err := a.GenerateFromString("password")
+	if err != nil {
+		t.Fatalf("GenerateFromString error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		hash       string
+		secret     string
+		wantMatch  bool
+		wantReason string
+		wantErr    error
+	}{
+		{"match", hash, "password", true, "", nil},
+		{"password mismatch", hash, "wrong", false, ReasonPasswordMismatch, nil},
+		{"version mismatch", strings.Replace(hash, "v=19", "v=16", 1), "password", false, ReasonVersionMismatch, ErrIncompatibleVersion},
+		{"malformed hash", "$argon2id$v=19$garbage", "password", false, ReasonMalformedHash, ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, reason, err := a.CheckSecretDetailed(tt.hash, []byte(tt.secret))
+			if match != tt.wantMatch || reason != tt.wantReason {
+				t.Errorf("got (%v, %q), want (%v, %q)", match, reason, tt.wantMatch, tt.wantReason)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateBatch(t *testing.T) {
+	a := NewArgon2PHC(&Argon2Config{memory: 8, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32})
+	secrets := [][]byte{[]byte("alpha"), []byte("bravo"), []byte("charlie"), []byte("delta"), []byte("echo")}
+
+	hashes, err := a.GenerateBatch(secrets, 2)
+	if err != nil {
+		t.Fatalf("GenerateBatch error: %v", err)
+	}
+	if len(hashes) != len(secrets) {
+		t.Fatalf("got %d hashes, want %d", len(hashes), len(secrets))
+	}
+	for i, hash := range hashes {
+		if match, err := a.CheckSecret(hash, secrets[i]); err != nil || !match {
+			t.Errorf("hash %d does not verify secret %q: %v", i, secrets[i], err)
+		}
+	}
+
+	if _, err := a.GenerateBatch(secrets, 0); !errors.Is(err, ErrInvalidConcurrency) {
+		t.Errorf("expected ErrInvalidConcurrency, got %v", err)
+	}
+	if hashes, err := a.GenerateBatch(nil, 4); err != nil || len(hashes) != 0 {
+		t.Errorf("empty batch = %v, %v", hashes, err)
+	}
+}
+
+func TestNewArgon2Config(t *testing.T) {
+	tests := []struct {
+		name        string
+		memory      uint32
+		iterations  uint32
+		parallelism uint8
+		saltLength  uint32
+		keyLength   uint32
+		wantErr     bool
+	}{
+		{"valid", 1024, 2, 2, 16, 32, false},
+		{"minimal", 8, 1, 1, 8, 16, false},
+		{"zero iterations", 1024, 0, 1, 16, 32, true},
+		{"zero parallelism", 1024, 1, 0, 16, 32, true},
+		{"memory below 8 KiB per lane", 31, 1, 4, 16, 32, true},
+		{"memory above cap", maxMemory + 1, 1, 1, 16, 32, true},
+		{"short salt", 1024, 1, 1, 7, 32, true},
+		{"short key", 1024, 1, 1, 16, 15, true},
+		{"long key", 1024, 1, 1, 16, 1025, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewArgon2Config(tt.memory, tt.iterations, tt.parallelism, tt.saltLength, tt.keyLength)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidConfig) {
+					t.Errorf("expected ErrInvalidConfig, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewArgon2Config error: %v", err)
+			}
+
+			hash, err := NewArgon2PHC(cfg).GenerateFromString("secret")
+			if err != nil {
+				t.Fatalf("GenerateFromString error: %v", err)
+			}
+			params, salt, key, err := ParsePHC(hash)
+			if err != nil {
+				t.Fatalf("ParsePHC error: %v", err)
+			}
+			if params.Memory != tt.memory || params.Iterations != tt.iterations || params.Parallelism != tt.parallelism ||
+				uint32(len(salt)) != tt.saltLength || uint32(len(key)) != tt.keyLength {
+				t.Errorf("hash %s does not match the configuration", hash)
+			}
+		})
+	}
+}
@@ -2,11 +2,21 @@ package phc
 
 import (
 	"crypto/rand"
+	"errors"
+	"io"
 	"math"
 	"strings"
 	"unicode"
 )
 
+var (
+	ErrPasswordMismatch = errors.New("password does not match hash")
+)
+
+// randReader is the source of salts. Tests may replace it with a
+// deterministic reader; production code must leave it as crypto/rand.
+var randReader io.Reader = rand.Reader
+
 type cryptoPHCBackendName string
 
 const (
@@ -19,27 +29,35 @@ type cryptoPHCBackend interface {
 
 	CheckSecret(encodedHash string, secret []byte) (bool, error)
 	CheckPassword(encodedHash, password string) (bool, error)
+
+	NeedsRehash(encodedHash string) bool
+}
+
+// backends maps each PHC algorithm identifier to a constructor for its
+// default backend. The identifier must match the first segment of the
+// PHC strings the backend produces, since CheckSecret dispatches on it.
+var backends = map[cryptoPHCBackendName]func() cryptoPHCBackend{
+	Argon2Id: func() cryptoPHCBackend { return newArgon2PHCDefault() },
 }
 
 type CryptoPHC struct {
-	backend cryptoPHCBackend
+	algorithm cryptoPHCBackendName
+	backend   cryptoPHCBackend
 }
 
 func GetDefault() *CryptoPHC {
-	return &CryptoPHC{
-		backend: newArgon2PHCDefault(),
-	}
+	return GetByAlgoName(Argon2Id)
 }
 
 func GetByAlgoName(backend cryptoPHCBackendName) *CryptoPHC {
-	switch backend {
-	case Argon2Id:
-		return &CryptoPHC{
-			backend: newArgon2PHCDefault(),
-		}
-	default:
+	newBackend, ok := backends[backend]
+	if !ok {
 		return nil
 	}
+	return &CryptoPHC{
+		algorithm: backend,
+		backend:   newBackend(),
+	}
 }
 
 func (c *CryptoPHC) GenerateFromString(password string) (string, error) {
@@ -50,18 +68,66 @@ func (c *CryptoPHC) GenerateFromBytes(secret []byte) (string, error) {
 	return c.backend.GenerateFromBytes(secret)
 }
 
+// CheckSecret verifies secret against encodedHash using the backend named by
+// the hash's algorithm segment, which may differ from c's own algorithm.
+// Hashes from unknown algorithms never match.
 func (c *CryptoPHC) CheckSecret(encodedHash string, secret []byte) (bool, error) {
-	vals := cryptoPHCBackendName(strings.Split(encodedHash, "$")[0])
-
-	switch vals {
-	case Argon2Id:
+	algorithm := algorithmName(encodedHash)
+	if algorithm == c.algorithm {
 		return c.backend.CheckSecret(encodedHash, secret)
-	default:
+	}
+
+	newBackend, ok := backends[algorithm]
+	if !ok {
 		return false, nil
 	}
+	return newBackend().CheckSecret(encodedHash, secret)
 }
+
 func (c *CryptoPHC) CheckPassword(encodedHash, password string) (bool, error) {
-	return c.backend.CheckPassword(encodedHash, password)
+	return c.CheckSecret(encodedHash, []byte(password))
+}
+
+// NeedsRehash reports whether encodedHash was produced by another algorithm
+// or with parameters that differ from c's current configuration.
+func (c *CryptoPHC) NeedsRehash(encodedHash string) bool {
+	if algorithmName(encodedHash) != c.algorithm {
+		return true
+	}
+	return c.backend.NeedsRehash(encodedHash)
+}
+
+// RehashIfNeeded verifies password against encodedHash and, when it matches
+// but NeedsRehash reports outdated parameters, returns a fresh hash to store
+// instead. Otherwise the original hash is returned with rehashed false. A
+// wrong password returns ErrPasswordMismatch.
+func (c *CryptoPHC) RehashIfNeeded(encodedHash, password string) (newHash string, rehashed bool, err error) {
+	match, err := c.CheckPassword(encodedHash, password)
+	if err != nil {
+		return "", false, err
+	}
+	if !match {
+		return "", false, ErrPasswordMismatch
+	}
+
+	if !c.NeedsRehash(encodedHash) {
+		return encodedHash, false, nil
+	}
+
+	newHash, err = c.GenerateFromString(password)
+	if err != nil {
+		return "", false, err
+	}
+	return newHash, true, nil
+}
+
+// algorithmName extracts the algorithm identifier from a "$algo$..." PHC string.
+func algorithmName(encodedHash string) cryptoPHCBackendName {
+	vals := strings.Split(encodedHash, "$")
+	if len(vals) < 2 || vals[0] != "" {
+		return ""
+	}
+	return cryptoPHCBackendName(vals[1])
 }
 
 func EstimateEntropy(password string) float64 {
@@ -107,7 +173,7 @@ func EstimateEntropy(password string) float64 {
 
 func generateRandomBytes(n uint32) ([]byte, error) {
 	b := make([]byte, n)
-	_, err := rand.Read(b)
+	_, err := io.ReadFull(randReader, b)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,124 @@
+package phc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestBackendsRoundTripThroughDispatcher(t *testing.T) {
+	const password = "correct horse battery staple"
+
+	for name := range backends {
+		t.Run(string(name), func(t *testing.T) {
+			c := GetByAlgoName(name)
+			if c == nil {
+				t.Fatalf("GetByAlgoName(%q) returned nil", name)
+			}
+
+			hash, err := c.GenerateFromString(password)
+			if err != nil {
+				t.Fatalf("GenerateFromString error: %v", err)
+			}
+
+			if got := algorithmName(hash); got != name {
+				t.Fatalf("hash algorithm segment = %q, want %q", got, name)
+			}
+
+			// Any dispatcher must route the hash to the backend that produced it.
+			for other := range backends {
+				match, err := GetByAlgoName(other).CheckPassword(hash, password)
+				if err != nil {
+					t.Fatalf("CheckPassword via %q error: %v", other, err)
+				}
+				if !match {
+					t.Errorf("CheckPassword via %q did not match a %q hash", other, name)
+				}
+			}
+
+			match, err := c.CheckPassword(hash, "wrong")
+			if err != nil {
+				t.Fatalf("CheckPassword error: %v", err)
+			}
+			if match {
+				t.Error("expected wrong password not to match")
+			}
+		})
+	}
+}
+
+func TestCheckSecretUnknownAlgorithm(t *testing.T) {
+	match, err := GetDefault().CheckPassword("$unknown$v=1$c2FsdA$aGFzaA", "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if match {
+		t.Error("expected unknown algorithm not to match")
+	}
+}
+
+func TestDeterministicRandReader(t *testing.T) {
+	defer func() { randReader = rand.Reader }()
+
+	a := NewArgon2PHC(&Argon2Config{memory: 1024, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32})
+
+	var hashes []string
+	for range 2 {
+		randReader = bytes.NewReader(bytes.Repeat([]byte{0x42}, 16))
+		hash, err := a.GenerateFromString("password")
+		if err != nil {
+			t.Fatalf("GenerateFromString error: %v", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if hashes[0] != hashes[1] {
+		t.Errorf("expected reproducible hashes, got %q and %q", hashes[0], hashes[1])
+	}
+}
+
+func TestRehashIfNeeded(t *testing.T) {
+	const password = "correct horse battery staple"
+
+	weak := &CryptoPHC{
+		algorithm: Argon2Id,
+		backend:   NewArgon2PHC(&Argon2Config{memory: 8, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32}),
+	}
+	current := &CryptoPHC{
+		algorithm: Argon2Id,
+		backend:   NewArgon2PHC(&Argon2Config{memory: 16, iterations: 2, parallelism: 1, saltLength: 16, keyLength: 32}),
+	}
+
+	oldHash, err := weak.GenerateFromString(password)
+	if err != nil {
+		t.Fatalf("GenerateFromString error: %v", err)
+	}
+
+	t.Run("rehash", func(t *testing.T) {
+		newHash, rehashed, err := current.RehashIfNeeded(oldHash, password)
+		if err != nil || !rehashed {
+			t.Fatalf("RehashIfNeeded = %v, %v; want rehash", rehashed, err)
+		}
+		if newHash == oldHash || current.NeedsRehash(newHash) {
+			t.Errorf("new hash %q should use the current parameters", newHash)
+		}
+		if match, _ := current.CheckPassword(newHash, password); !match {
+			t.Error("new hash does not verify")
+		}
+	})
+
+	t.Run("no rehash", func(t *testing.T) {
+		newHash, rehashed, err := weak.RehashIfNeeded(oldHash, password)
+		if err != nil || rehashed || newHash != oldHash {
+			t.Errorf("RehashIfNeeded = %q, %v, %v; want original hash", newHash, rehashed, err)
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		newHash, rehashed, err := current.RehashIfNeeded(oldHash, "wrong")
+		if !errors.Is(err, ErrPasswordMismatch) || rehashed || newHash != "" {
+			t.Errorf("RehashIfNeeded = %q, %v, %v; want ErrPasswordMismatch", newHash, rehashed, err)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package phc
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")
+)
+
+// PHCParams are the algorithm parameters encoded in a PHC string.
+type PHCParams struct {
+	Algorithm   cryptoPHCBackendName
+	Version     int
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// ParsePHC splits a "$algo$v=..$m=..,t=..,p=..$salt$hash" string into its
+// parameters, salt and hash without verifying anything. Hashes without the
+// "v=" segment, as emitted by some implementations, are read as the current
+// argon2 version. Cost parameters argon2 cannot run with, or memory above
+// 4 GiB, return ErrInvalidHash.
+func ParsePHC(encodedHash string) (params PHCParams, salt, hash []byte, err error) {
+	vals := strings.Split(encodedHash, "$")
+	if len(vals) == 5 && vals[0] == "" {
+		// No version segment: insert the current version so indices line up.
+		vals = append(vals[:2], append([]string{fmt.Sprintf("v=%d", argon2.Version)}, vals[2:]...)...)
+	}
+	if len(vals) != 6 || vals[0] != "" {
+		return PHCParams{}, nil, nil, ErrInvalidHash
+	}
+
+	params.Algorithm = cryptoPHCBackendName(vals[1])
+	if params.Algorithm != Argon2Id {
+		return PHCParams{}, nil, nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidHash, vals[1])
+	}
+
+	if _, err := fmt.Sscanf(vals[2], "v=%d", &params.Version); err != nil {
+		return PHCParams{}, nil, nil, ErrInvalidHash
+	}
+	if params.Version != argon2.Version {
+		return PHCParams{}, nil, nil, ErrIncompatibleVersion
+	}
+
+	if _, err := fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return PHCParams{}, nil, nil, ErrInvalidHash
+	}
+	if err := params.validateCost(); err != nil {
+		return PHCParams{}, nil, nil, err
+	}
+
+	salt, err = base64.RawStdEncoding.Strict().DecodeString(vals[4])
+	if err != nil || len(salt) == 0 {
+		return PHCParams{}, nil, nil, ErrInvalidHash
+	}
+
+	hash, err = base64.RawStdEncoding.Strict().DecodeString(vals[5])
+	if err != nil || len(hash) == 0 {
+		return PHCParams{}, nil, nil, ErrInvalidHash
+	}
+
+	return params, salt, hash, nil
+}
+
+// validateCost checks the argon2 cost parameters against the bounds argon2
+// itself requires (t >= 1, p >= 1, m >= 8*p KiB) and against maxMemory.
+func (p PHCParams) validateCost() error {
+	switch {
+	case p.Iterations < 1:
+		return fmt.Errorf("%w: iterations must be at least 1", ErrInvalidHash)
+	case p.Parallelism < 1:
+		return fmt.Errorf("%w: parallelism must be at least 1", ErrInvalidHash)
+	case p.Memory < 8*uint32(p.Parallelism):
+		return fmt.Errorf("%w: memory must be at least %d KiB for parallelism %d", ErrInvalidHash, 8*uint32(p.Parallelism), p.Parallelism)
+	case p.Memory > maxMemory:
+		return fmt.Errorf("%w: memory must be at most %d KiB", ErrInvalidHash, maxMemory)
+	}
+	return nil
+}
+
+// EncodePHC assembles the canonical PHC string for params, salt and hash.
+// It is the inverse of ParsePHC.
+func EncodePHC(params PHCParams, salt, hash []byte) (string, error) {
+	if params.Algorithm != Argon2Id {
+		return "", fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidHash, params.Algorithm)
+	}
+	if params.Version != argon2.Version {
+		return "", ErrIncompatibleVersion
+	}
+	if err := params.validateCost(); err != nil {
+		return "", err
+	}
+	if len(salt) == 0 || len(hash) == 0 {
+		return "", fmt.Errorf("%w: salt and hash must not be empty", ErrInvalidHash)
+	}
+
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		params.Algorithm, params.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+// PHCEqual reports whether a and b encode the same algorithm, parameters,
+// salt and digest, e.g. to deduplicate credential stores. Equivalent
+// spellings, such as a missing "v=" segment, compare equal. The salt and
+// digest are compared in constant time; malformed hashes and hashes of
+// unsupported algorithms are never equal.
+func PHCEqual(a, b string) bool {
+	paramsA, saltA, hashA, err := ParsePHC(a)
+	if err != nil {
+		return false
+	}
+	paramsB, saltB, hashB, err := ParsePHC(b)
+	if err != nil {
+		return false
+	}
+	if paramsA != paramsB {
+		return false
+	}
+
+	saltEqual := subtle.ConstantTimeCompare(saltA, saltB)
+	hashEqual := subtle.ConstantTimeCompare(hashA, hashB)
+	return saltEqual&hashEqual == 1
+}
+
+// Inspect reports the algorithm and parameters of an encoded hash without
+// verifying it, e.g. to survey which schemes and costs a user table still
+// uses. Parameters are returned as written ("v", "m", "t", "p" for argon2id),
+// so hashes with outdated versions can be inspected too. Hashes from
+// algorithms without a registered backend return ErrUnknownAlgorithm.
+func Inspect(encodedHash string) (algo string, params map[string]string, err error) {
+	vals := strings.Split(encodedHash, "$")
+	if len(vals) < 3 || vals[0] != "" || vals[1] == "" {
+		return "", nil, ErrInvalidHash
+	}
+
+	algo = vals[1]
+	if _, ok := backends[cryptoPHCBackendName(algo)]; !ok {
+		return "", nil, fmt.Errorf("%w %q", ErrUnknownAlgorithm, algo)
+	}
+
+	params = make(map[string]string)
+	for _, segment := range vals[2:] {
+		if !strings.Contains(segment, "=") {
+			continue // salt or hash
+		}
+		for _, pair := range strings.Split(segment, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || key == "" {
+				return "", nil, ErrInvalidHash
+			}
+			params[key] = value
+		}
+	}
+	return algo, params, nil
+}
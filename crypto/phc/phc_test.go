@@ -0,0 +1,228 @@
+package phc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestEncodePHCRoundTrip(t *testing.T) {
+	a := NewArgon2PHC(&Argon2Config{memory: 1024, iterations: 2, parallelism: 1, saltLength: 16, keyLength: 32})
+
+	hash, err := a.GenerateFromString("password")
+	if err != nil {
+		t.Fatalf("GenerateFromString error: %v", err)
+	}
+
+	params, salt, digest, err := ParsePHC(hash)
+	if err != nil {
+		t.Fatalf("ParsePHC error: %v", err)
+	}
+	if params.Memory != 1024 || params.Iterations != 2 || params.Parallelism != 1 {
+		t.Errorf("unexpected params %+v", params)
+	}
+
+	encoded, err := EncodePHC(params, salt, digest)
+	if err != nil {
+		t.Fatalf("EncodePHC error: %v", err)
+	}
+	if encoded != hash {
+		t.Errorf("EncodePHC(ParsePHC(h)) = %q, want %q", encoded, hash)
+	}
+}
+
+func TestEncodePHCValidation(t *testing.T) {
+	valid := PHCParams{Algorithm: Argon2Id, Version: 19, Memory: 1024, Iterations: 1, Parallelism: 1}
+	salt := bytes.Repeat([]byte{1}, 16)
+	hash := bytes.Repeat([]byte{2}, 32)
+
+	tests := []struct {
+		name    string
+		params  PHCParams
+		salt    []byte
+		hash    []byte
+		wantErr error
+	}{
+		{"unknown algorithm", PHCParams{Algorithm: "md5", Version: 19, Memory: 1, Iterations: 1, Parallelism: 1}, salt, hash, ErrInvalidHash},
+		{"wrong version", PHCParams{Algorithm: Argon2Id, Version: 16, Memory: 1, Iterations: 1, Parallelism: 1}, salt, hash, ErrIncompatibleVersion},
+		{"zero iterations", PHCParams{Algorithm: Argon2Id, Version: 19, Memory: 1024, Parallelism: 1}, salt, hash, ErrInvalidHash},
+		{"memory below 8 KiB per lane", PHCParams{Algorithm: Argon2Id, Version: 19, Memory: 15, Iterations: 1, Parallelism: 2}, salt, hash, ErrInvalidHash},
+		{"empty salt", valid, nil, hash, ErrInvalidHash},
+		{"empty hash", valid, salt, nil, ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EncodePHC(tt.params, tt.salt, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		name       string
+		hash       string
+		wantAlgo   string
+		wantParams map[string]string
+		wantErr    error
+	}{
+		{
+			name:       "argon2id",
+			hash:       "$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+			wantAlgo:   "argon2id",
+			wantParams: map[string]string{"v": "19", "m": "65536", "t": "3", "p": "2"},
+		},
+		{
+			name:       "argon2id with outdated version",
+			hash:       "$argon2id$v=16$m=4096,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+			wantAlgo:   "argon2id",
+			wantParams: map[string]string{"v": "16", "m": "4096", "t": "1", "p": "1"},
+		},
+		{
+			// bcrypt has no backend yet, so it is reported as unknown.
+			name:    "bcrypt",
+			hash:    "$2b$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy",
+			wantErr: ErrUnknownAlgorithm,
+		},
+		{name: "malformed", hash: "argon2id", wantErr: ErrInvalidHash},
+		{name: "bad param", hash: "$argon2id$v=19$m=1,=3$c2FsdA$aGFzaA", wantErr: ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			algo, params, err := Inspect(tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Inspect error = %v, want %v", err, tt.wantErr)
+			}
+			if algo != tt.wantAlgo {
+				t.Errorf("algo = %q, want %q", algo, tt.wantAlgo)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestParsePHCVersionSegment(t *testing.T) {
+	a := NewArgon2PHC(&Argon2Config{memory: 8, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32})
+	withVersion, err := a.GenerateFromString("password")
+	if err != nil {
+		t.Fatalf("GenerateFromString error: %v", err)
+	}
+	withoutVersion := strings.Replace(withVersion, "$v=19", "", 1)
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"6 segments with version", withVersion},
+		{"5 segments without version", withoutVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _, _, err := ParsePHC(tt.hash)
+			if err != nil {
+				t.Fatalf("ParsePHC error: %v", err)
+			}
+			if params.Version != argon2.Version || params.Memory != 8 || params.Iterations != 1 || params.Parallelism != 1 {
+				t.Errorf("unexpected params %+v", params)
+			}
+
+			match, err := a.CheckPassword(tt.hash, "password")
+			if err != nil || !match {
+				t.Errorf("CheckPassword = %v, %v; want true, nil", match, err)
+			}
+		})
+	}
+}
+
+func TestPHCEqual(t *testing.T) {
+	a := NewArgon2PHC(&Argon2Config{memory: 1024, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32})
+	hash1, err := a.GenerateFromString("hunter2")
+	if err != nil {
+		t.Fatalf("GenerateFromString error: %v", err)
+	}
+	hash2, err := a.GenerateFromString("hunter2")
+	if err != nil {
+		t.Fatalf("GenerateFromString error: %v", err)
+	}
+
+	withoutVersion := strings.Replace(hash1, fmt.Sprintf("$v=%d", argon2.Version), "", 1)
+	otherAlgorithm := strings.Replace(hash1, "$argon2id$", "$argon2i$", 1)
+	// Parseable variants of hash1 that differ in one field only, so the
+	// comparison itself has to tell them apart.
+	otherParams := strings.Replace(hash1, "$m=1024,t=1,p=1$", "$m=2048,t=1,p=1$", 1)
+	fields1, fields2 := strings.Split(hash1, "$"), strings.Split(hash2, "$")
+	otherKey := strings.Join(fields1[:len(fields1)-1], "$") + "$" + fields2[len(fields2)-1]
+	for _, h := range []string{otherParams, otherKey} {
+		if _, _, _, err := ParsePHC(h); err != nil {
+			t.Fatalf("ParsePHC(%q) error: %v", h, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"identical", hash1, hash1, true},
+		{"missing version segment", hash1, withoutVersion, true},
+		{"same password different salt", hash1, hash2, false},
+		{"different parameters", hash1, otherParams, false},
+		{"same salt different key", hash1, otherKey, false},
+		{"unsupported algorithm", hash1, otherAlgorithm, false},
+		{"malformed", hash1, "not-a-hash", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PHCEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("PHCEqual = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePHCCostBounds(t *testing.T) {
+	const saltAndKey = "$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA"
+	a := NewArgon2PHC(&Argon2Config{memory: 8, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32})
+
+	tests := []struct {
+		name    string
+		params  string
+		wantErr bool
+	}{
+		{"minimum", "m=8,t=1,p=1", false},
+		{"zero iterations", "m=8,t=0,p=1", true},
+		{"zero parallelism", "m=8,t=1,p=0", true},
+		{"memory below 8 KiB per lane", "m=15,t=1,p=2", true},
+		{"memory above cap", fmt.Sprintf("m=%d,t=1,p=1", maxMemory+1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := "$argon2id$v=19$" + tt.params + saltAndKey
+			_, _, _, err := ParsePHC(hash)
+			if tt.wantErr != errors.Is(err, ErrInvalidHash) {
+				t.Fatalf("ParsePHC error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				return
+			}
+			// Verification must fail cleanly rather than reach argon2.
+			match, err := a.CheckPassword(hash, "password")
+			if match || !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("CheckPassword = %v, %v; want false, ErrInvalidHash", match, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package phc
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// hibpRangeURL is the HaveIBeenPwned k-anonymity range endpoint.
+const hibpRangeURL = "https://api.pwnedpasswords.com/range/"
+
+// HIBPClient is the HTTP client used by IsPwned. Replace it to stub the API
+// in tests or to route requests through a proxy.
+var HIBPClient = http.DefaultClient
+
+// IsPwned reports how many times password appears in the HaveIBeenPwned
+// breach corpus. Only the first 5 hex characters of the password's SHA-1
+// leave the process. It requires network access and is never called
+// implicitly by this package.
+func IsPwned(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hibpRangeURL+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := HIBPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("pwned passwords API returned %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		hashSuffix, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(hashSuffix, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("invalid count in pwned passwords response: %w", err)
+		}
+		return n, nil
+	}
+	return 0, scanner.Err()
+}
@@ -0,0 +1,52 @@
+package phc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestIsPwned(t *testing.T) {
+	// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	const rangeResponse = "003D68EB55068C33ACE09247EE4C639306B:3\r\n" +
+		"1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n" +
+		"011053FD0102E94D6AE2F8B83D76FAF94F6:0\r\n"
+
+	defer func() { HIBPClient = http.DefaultClient }()
+
+	var requestedURL string
+	HIBPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requestedURL = r.URL.String()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(rangeResponse)),
+		}, nil
+	})}
+
+	count, err := IsPwned(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("IsPwned error: %v", err)
+	}
+	if count != 9659365 {
+		t.Errorf("count = %d, want %d", count, 9659365)
+	}
+	if requestedURL != hibpRangeURL+"5BAA6" {
+		t.Errorf("requested %q, want only the hash prefix to be sent", requestedURL)
+	}
+
+	count, err = IsPwned(context.Background(), "a-password-not-in-the-response")
+	if err != nil {
+		t.Fatalf("IsPwned error: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("count = %d, want 0", count)
+	}
+}
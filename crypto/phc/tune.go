@@ -0,0 +1,85 @@
+package phc
+
+import (
+	"time"
+)
+
+// BenchResult is the measured hashing time of one argon2 configuration.
+type BenchResult struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	Duration    time.Duration
+}
+
+// TuneResult is the outcome of TuneArgon2.
+type TuneResult struct {
+	// Results holds one measurement per combination, memory-major.
+	Results []BenchResult
+	// Best is the strongest configuration hashing within the target, or the
+	// fastest one measured if WithinTarget is false.
+	Best         BenchResult
+	WithinTarget bool
+}
+
+// TuneArgon2 hashes once with every combination of memories (KiB) and
+// iterations at the given parallelism on the current machine, and picks the
+// strongest configuration, by memory times iterations, that takes at most
+// target. Invalid combinations return ErrInvalidConfig before anything is
+// measured.
+func TuneArgon2(target time.Duration, memories, iterations []uint32, parallelism uint8) (*TuneResult, error) {
+	var configs []*Argon2Config
+	for _, memory := range memories {
+		for _, iter := range iterations {
+			cfg, err := NewArgon2Config(memory, iter, parallelism, 16, 32)
+			if err != nil {
+				return nil, err
+			}
+			configs = append(configs, cfg)
+		}
+	}
+	if len(configs) == 0 {
+		return nil, ErrInvalidConfig
+	}
+
+	res := &TuneResult{}
+	for _, cfg := range configs {
+		r := BenchResult{
+			Memory:      cfg.memory,
+			Iterations:  cfg.iterations,
+			Parallelism: cfg.parallelism,
+			Duration:    NewArgon2PHC(cfg).EstimateCost(),
+		}
+		res.Results = append(res.Results, r)
+
+		switch {
+		case r.Duration <= target && (!res.WithinTarget || stronger(r, res.Best)):
+			res.Best, res.WithinTarget = r, true
+		case !res.WithinTarget && (len(res.Results) == 1 || r.Duration < res.Best.Duration):
+			res.Best = r
+		}
+	}
+	return res, nil
+}
+
+// stronger reports whether a costs an attacker more than b: more memory
+// times iterations, or more memory at equal cost.
+func stronger(a, b BenchResult) bool {
+	costA, costB := uint64(a.Memory)*uint64(a.Iterations), uint64(b.Memory)*uint64(b.Iterations)
+	if costA != costB {
+		return costA > costB
+	}
+	return a.Memory > b.Memory
+}
+
+// Config returns the configuration measured by r, with rum's default salt
+// and key lengths.
+func (r BenchResult) Config() *Argon2Config {
+	return &Argon2Config{
+		memory:      r.Memory,
+		iterations:  r.Iterations,
+		parallelism: r.Parallelism,
+		saltLength:  16,
+		keyLength:   32,
+	}
+}
@@ -0,0 +1,36 @@
+package phc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTuneArgon2(t *testing.T) {
+	res, err := TuneArgon2(time.Hour, []uint32{64, 128}, []uint32{1, 2}, 1)
+	if err != nil {
+		t.Fatalf("TuneArgon2 error: %v", err)
+	}
+	if len(res.Results) != 4 {
+		t.Fatalf("got %d results, want 4", len(res.Results))
+	}
+	// With a generous target the strongest combination wins.
+	if !res.WithinTarget || res.Best.Memory != 128 || res.Best.Iterations != 2 {
+		t.Errorf("best = %+v, want 128 KiB x 2", res.Best)
+	}
+	if cfg := res.Best.Config(); cfg.memory != 128 || cfg.iterations != 2 || cfg.parallelism != 1 {
+		t.Errorf("Config() = %+v", cfg)
+	}
+
+	res, err = TuneArgon2(0, []uint32{64, 128}, []uint32{1}, 1)
+	if err != nil {
+		t.Fatalf("TuneArgon2 error: %v", err)
+	}
+	if res.WithinTarget {
+		t.Error("nothing should hash within a zero target")
+	}
+
+	if _, err := TuneArgon2(time.Second, []uint32{4}, []uint32{1}, 1); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for 4 KiB, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Casing selects how JSONResponseCased rewrites object keys.
+type Casing int
+
+const (
+	SnakeCase Casing = iota + 1 // user_id
+	CamelCase                   // userId
+)
+
+// JSONResponseCased is like JSONResponse but rewrites every object key in
+// data to the requested casing, regardless of the Go struct tags. The
+// envelope fields keep their usual names.
+func JSONResponseCased(w http.ResponseWriter, casing Casing, message string, data any, statusCodes ...int) {
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(encoded))
+		dec.UseNumber()
+		var generic any
+		if err := dec.Decode(&generic); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data = recaseKeys(generic, casing)
+	}
+
+	JSONResponse(w, message, data, statusCodes...)
+}
+
+// recaseKeys rewrites the keys of all objects nested in v.
+func recaseKeys(v any, casing Casing) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			out[recase(k, casing)] = recaseKeys(val, casing)
+		}
+		return out
+	case []any:
+		for i, val := range v {
+			v[i] = recaseKeys(val, casing)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// recase converts a single key to the requested casing.
+func recase(key string, casing Casing) string {
+	words := splitWords(key)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if casing == CamelCase && i > 0 {
+			r, size := utf8.DecodeRuneInString(word)
+			word = string(unicode.ToUpper(r)) + word[size:]
+		}
+		words[i] = word
+	}
+
+	if casing == CamelCase {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words, "_")
+}
+
+// splitWords splits an identifier on separators and case changes, keeping
+// acronyms together: "HTTPServerID" -> ["HTTP", "Server", "ID"].
+func splitWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := -1
+
+	for i, r := range runes {
+		if r == '_' || r == '-' || unicode.IsSpace(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestJSONResponseCased(t *testing.T) {
+	type address struct {
+		StreetName string
+		ZipCode    string `json:"zip_code"`
+	}
+	data := struct {
+		UserID    int64
+		FirstName string `json:"first_name"`
+		HTTPProxy string
+		Addresses []address
+	}{
+		UserID:    9007199254740993,
+		FirstName: "Ada",
+		HTTPProxy: "none",
+		Addresses: []address{{StreetName: "Main", ZipCode: "00100"}},
+	}
+
+	tests := []struct {
+		casing Casing
+		want   map[string]any
+	}{
+		{SnakeCase, map[string]any{
+			"user_id":    json.Number("9007199254740993"),
+			"first_name": "Ada",
+			"http_proxy": "none",
+			"addresses":  []any{map[string]any{"street_name": "Main", "zip_code": "00100"}},
+		}},
+		{CamelCase, map[string]any{
+			"userId":    json.Number("9007199254740993"),
+			"firstName": "Ada",
+			"httpProxy": "none",
+			"addresses": []any{map[string]any{"streetName": "Main", "zipCode": "00100"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		JSONResponseCased(rec, tt.casing, "ok", data)
+
+		var resp struct {
+			Data map[string]any `json:"data"`
+		}
+		dec := json.NewDecoder(rec.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+
+		if !reflect.DeepEqual(resp.Data, tt.want) {
+			t.Errorf("casing %d: data = %v, want %v", tt.casing, resp.Data, tt.want)
+		}
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"userID", []string{"user", "ID"}},
+		{"HTTPServerID", []string{"HTTP", "Server", "ID"}},
+		{"first_name", []string{"first", "name"}},
+		{"Page2Title", []string{"Page2", "Title"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitWords(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWords(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRecase(t *testing.T) {
+	tests := []struct {
+		input  string
+		casing Casing
+		want   string
+	}{
+		{"first_name", CamelCase, "firstName"},
+		{"user_éclair", CamelCase, "userÉclair"},
+		{"prix_ünit", CamelCase, "prixÜnit"},
+		{"UserÉclair", SnakeCase, "user_éclair"},
+	}
+
+	for _, tt := range tests {
+		if got := recase(tt.input, tt.casing); got != tt.want {
+			t.Errorf("recase(%q, %d) = %q, want %q", tt.input, tt.casing, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrLimitExceeded = errors.New("source exceeds the byte limit")
+)
+
+// CopyN copies src to dst until EOF, like io.Copy, but never more than limit
+// bytes. It returns the number of bytes copied and ErrLimitExceeded if src
+// held more than limit bytes; exactly limit bytes is not an error. Unlike
+// io.CopyN, reaching EOF before limit is not an error either. On
+// ErrLimitExceeded, dst holds the first limit bytes and one more byte has
+// been consumed from src.
+func CopyN(dst io.Writer, src io.Reader, limit int64) (int64, error) {
+	if limit < 0 {
+		return 0, fmt.Errorf("negative limit %d", limit)
+	}
+
+	n, err := io.CopyN(dst, src, limit)
+	if errors.Is(err, io.EOF) {
+		return n, nil
+	}
+	if err != nil {
+		return n, err
+	}
+
+	// Exactly limit bytes were copied; the source must now be at EOF.
+	var probe [1]byte
+	switch _, err := io.ReadFull(src, probe[:]); {
+	case err == nil:
+		return n, ErrLimitExceeded
+	case errors.Is(err, io.EOF):
+		return n, nil
+	default:
+		return n, err
+	}
+}
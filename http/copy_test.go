@@ -0,0 +1,41 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCopyN(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		limit   int64
+		want    string
+		wantErr error
+	}{
+		{"under limit", "abc", 5, "abc", nil},
+		{"exactly at limit", "abcde", 5, "abcde", nil},
+		{"over limit", "abcdef", 5, "abcde", ErrLimitExceeded},
+		{"empty source", "", 5, "", nil},
+		{"zero limit", "a", 0, "", ErrLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst bytes.Buffer
+			n, err := CopyN(&dst, strings.NewReader(tt.src), tt.limit)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if dst.String() != tt.want || n != int64(len(tt.want)) {
+				t.Errorf("copied %d bytes %q, want %q", n, dst.String(), tt.want)
+			}
+		})
+	}
+
+	if _, err := CopyN(&bytes.Buffer{}, strings.NewReader("a"), -1); err == nil {
+		t.Error("expected error for a negative limit")
+	}
+}
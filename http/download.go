@@ -0,0 +1,93 @@
+package http
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/4Sigma/rum/crypto/block_cipher"
+)
+
+// FileDownload streams r to the client as an attachment named filename.
+// An empty contentType defaults to application/octet-stream.
+func FileDownload(w http.ResponseWriter, r io.Reader, filename string, contentType string) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", contentDisposition(filename))
+	w.WriteHeader(http.StatusOK)
+
+	_, err := io.Copy(w, r)
+	return err
+}
+
+// contentDisposition builds an attachment header value for a sanitized filename.
+func contentDisposition(filename string) string {
+	name := sanitizeFilename(filename)
+	if name == "" {
+		return "attachment"
+	}
+	if v := mime.FormatMediaType("attachment", map[string]string{"filename": name}); v != "" {
+		return v
+	}
+	return "attachment"
+}
+
+// sanitizeFilename drops directory components and control characters so the
+// name cannot break out of the Content-Disposition header.
+func sanitizeFilename(filename string) string {
+	filename = strings.ReplaceAll(filename, `\`, "/")
+	filename = path.Base(filename)
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, filename)
+
+	if filename == "." || filename == "/" || filename == ".." {
+		return ""
+	}
+	return strings.TrimSpace(filename)
+}
+
+// EncryptedDownload streams r to the client as an attachment, encrypted with
+// block_cipher.EncryptStream on the fly. Errors reading r or encrypting are
+// returned like FileDownload's. By then the headers and part of the body have
+// been sent, so the caller should abort the response, e.g. with
+// panic(http.ErrAbortHandler), to give the client a truncated transfer rather
+// than a silently corrupt file.
+func EncryptedDownload(w http.ResponseWriter, r io.Reader, password []byte, filename string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(block_cipher.EncryptStream(pw, r, password))
+	}()
+
+	err := FileDownload(w, pr, filename, "application/octet-stream")
+	// Unblock the encrypting goroutine if the copy stopped early.
+	pr.CloseWithError(err)
+	return err
+}
+
+// ServeDecrypted serves ciphertext, written by block_cipher.EncryptStream,
+// decrypted on the fly with http.ServeContent semantics: Range and
+// conditional requests are honoured using name and modtime, and
+// unsatisfiable ranges get 416. Range support relies on seeking, which is
+// why ciphertext must be an io.ReadSeeker (e.g. an *os.File); a ciphertext
+// that cannot be decrypted gets a 500 response.
+func ServeDecrypted(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, ciphertext io.ReadSeeker, password []byte) {
+	content, err := block_cipher.NewDecryptingReader(ciphertext, password)
+	if err != nil {
+		log.Printf("rum: decrypting %q failed: %v", name, err)
+		JSONResponse(w, "failed to decrypt content", nil, http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, name, modtime, content)
+}
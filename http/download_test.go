@@ -0,0 +1,139 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/4Sigma/rum/crypto/block_cipher"
+)
+
+func TestFileDownload(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := FileDownload(rec, strings.NewReader("report data"), "report.csv", "text/csv")
+	if err != nil {
+		t.Fatalf("FileDownload error: %v", err)
+	}
+
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=report.csv` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if rec.Body.String() != "report data" {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
+
+func TestFileDownloadSanitizesFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"evil\r\nSet-Cookie: x=1.txt", `attachment; filename="evilSet-Cookie: x=1.txt"`},
+		{`..\..\secret "name".txt`, `attachment; filename="secret name.txt"`},
+		{"../", "attachment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			FileDownload(rec, strings.NewReader(""), tt.filename, "")
+
+			if got := rec.Header().Get("Content-Disposition"); got != tt.want {
+				t.Errorf("Content-Disposition = %q, want %q", got, tt.want)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/octet-stream" {
+				t.Errorf("Content-Type = %q", got)
+			}
+		})
+	}
+}
+
+func TestEncryptedDownload(t *testing.T) {
+	plain := bytes.Repeat([]byte("confidential "), 5000)
+	password := []byte("s3cr3t")
+
+	rec := httptest.NewRecorder()
+	if err := EncryptedDownload(rec, bytes.NewReader(plain), password, "backup.bin.enc"); err != nil {
+		t.Fatalf("EncryptedDownload error: %v", err)
+	}
+
+	if got := rec.Header().Get("Content-Disposition"); got != "attachment; filename=backup.bin.enc" {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	var decrypted bytes.Buffer
+	if err := block_cipher.DecryptStream(&decrypted, rec.Body, password); err != nil {
+		t.Fatalf("DecryptStream error: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plain) {
+		t.Error("decrypted body does not match the input")
+	}
+}
+
+func TestEncryptedDownloadReadError(t *testing.T) {
+	diskFailure := errors.New("disk failure")
+	r := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(diskFailure))
+
+	err := EncryptedDownload(httptest.NewRecorder(), r, []byte("s3cr3t"), "backup.bin.enc")
+	if !errors.Is(err, diskFailure) {
+		t.Errorf("expected the read error, got %v", err)
+	}
+}
+
+func TestServeDecrypted(t *testing.T) {
+	password := []byte("media password")
+	plain := bytes.Repeat([]byte("0123456789abcdef-"), 1000)
+
+	var encrypted bytes.Buffer
+	if err := block_cipher.EncryptStream(&encrypted, bytes.NewReader(plain), password); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		rangeHdr   string
+		wantStatus int
+		wantBody   []byte
+	}{
+		{"full", "", http.StatusOK, plain},
+		{"range", "bytes=100-149", http.StatusPartialContent, plain[100:150]},
+		{"suffix", "bytes=-10", http.StatusPartialContent, plain[len(plain)-10:]},
+		{"unsatisfiable", "bytes=999999-", http.StatusRequestedRangeNotSatisfiable, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/media.bin", nil)
+			if tt.rangeHdr != "" {
+				req.Header.Set("Range", tt.rangeHdr)
+			}
+			rec := httptest.NewRecorder()
+			ServeDecrypted(rec, req, "media.bin", time.Time{}, bytes.NewReader(encrypted.Bytes()), password)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != nil && !bytes.Equal(rec.Body.Bytes(), tt.wantBody) {
+				t.Errorf("body = %q, want %q", rec.Body.Bytes(), tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("truncated ciphertext", func(t *testing.T) {
+		truncated := encrypted.Bytes()[:encrypted.Len()-5]
+		rec := httptest.NewRecorder()
+		ServeDecrypted(rec, httptest.NewRequest(http.MethodGet, "/", nil), "media.bin", time.Time{}, bytes.NewReader(truncated), password)
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", rec.Code)
+		}
+	})
+}
@@ -1,45 +1,181 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+
+	rumtpl "github.com/4Sigma/rum/template_manager"
 )
 
 type MalformedRequest struct {
-	Status int
-	Msg    string
+	Status int    `json:"status"`
+	Msg    string `json:"message"`
+	// Errors holds the individual errors combined by MultiMalformed.
+	Errors []*MalformedRequest `json:"-"`
 }
 
 func (mr *MalformedRequest) Error() string {
 	return mr.Msg
 }
 
+// MultiMalformed combines the errors found while validating several parts of
+// a request into one, with the most severe (highest) status and the messages
+// joined by "; ". Errors that are not a *MalformedRequest count as a generic
+// 500. Nil errors are skipped; if all are nil, MultiMalformed returns nil.
+func MultiMalformed(errs ...error) *MalformedRequest {
+	var parts []*MalformedRequest
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var mr *MalformedRequest
+		switch {
+		case errors.As(err, &mr) && len(mr.Errors) > 0:
+			parts = append(parts, mr.Errors...)
+		case mr != nil:
+			parts = append(parts, mr)
+		default:
+			parts = append(parts, &MalformedRequest{
+				Status: http.StatusInternalServerError,
+				Msg:    http.StatusText(http.StatusInternalServerError),
+			})
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+
+	combined := &MalformedRequest{Errors: parts}
+	msgs := make([]string, len(parts))
+	for i, part := range parts {
+		combined.Status = max(combined.Status, part.Status)
+		msgs[i] = part.Msg
+	}
+	combined.Msg = strings.Join(msgs, "; ")
+	return combined
+}
+
+// WriteMalformed writes err as a JSON error response: a *MalformedRequest uses
+// its own status and message, anything else becomes a generic 500. Errors
+// combined by MultiMalformed are also listed individually, with their status
+// and message, as the response data.
+func WriteMalformed(w http.ResponseWriter, err error) {
+	var mr *MalformedRequest
+	if errors.As(err, &mr) {
+		var data any
+		if len(mr.Errors) > 0 {
+			data = mr.Errors
+		}
+		JSONResponse(w, mr.Msg, data, mr.Status)
+		return
+	}
+	JSONResponse(w, http.StatusText(http.StatusInternalServerError), nil, http.StatusInternalServerError)
+}
+
 const maxBodySize = 200 << 20 // 200 MB
 
-func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst any) error {
-	ct := r.Header.Get("Content-Type")
-	if ct != "" {
-		mediaType := strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
-		if mediaType != "application/json" {
-			msg := "Content-Type header is not application/json"
-			return &MalformedRequest{Status: http.StatusUnsupportedMediaType, Msg: msg}
+// DecodeOption customizes how DecodeJSONBody and DecodeJSONBodyRaw decode.
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	useNumber bool
+	maxDepth  int
+}
+
+// defaultMaxDepth bounds how deeply arrays and objects may nest in a request
+// body unless WithMaxDepth says otherwise.
+const defaultMaxDepth = 64
+
+// WithUseNumber decodes numbers held in interface values (any, map[string]any)
+// as json.Number instead of float64, so 64-bit IDs keep full precision.
+func WithUseNumber() DecodeOption {
+	return func(o *decodeOptions) {
+		o.useNumber = true
+	}
+}
+
+// WithMaxDepth rejects bodies whose arrays and objects nest deeper than depth
+// with a 400, protecting handlers from stack exhaustion. Values below 1 keep
+// the default of 64.
+func WithMaxDepth(depth int) DecodeOption {
+	return func(o *decodeOptions) {
+		if depth > 0 {
+			o.maxDepth = depth
 		}
 	}
+}
+
+func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst any, opts ...DecodeOption) error {
+	if err := limitJSONBody(w, r); err != nil {
+		return err
+	}
+	return decodeJSON(r.Body, dst, opts...)
+}
+
+// DecodeJSONBodyRaw decodes like DecodeJSONBody and also returns the exact
+// bytes read from the (size-limited) body, e.g. for audit logs. The raw
+// bytes are returned even when decoding fails.
+func DecodeJSONBodyRaw(w http.ResponseWriter, r *http.Request, dst any, opts ...DecodeOption) ([]byte, error) {
+	if err := limitJSONBody(w, r); err != nil {
+		return nil, err
+	}
+
+	var raw bytes.Buffer
+	err := decodeJSON(io.TeeReader(r.Body, &raw), dst, opts...)
+	return raw.Bytes(), err
+}
+
+// limitJSONBody checks the request content type and caps the body size.
+func limitJSONBody(w http.ResponseWriter, r *http.Request) error {
+	ct := r.Header.Get("Content-Type")
+	if ct != "" && !isJSONContentType(ct) {
+		msg := "Content-Type header is not application/json"
+		return &MalformedRequest{Status: http.StatusUnsupportedMediaType, Msg: msg}
+	}
 
 	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	return nil
+}
+
+// decodeJSON decodes a single JSON object from body into dst, translating
+// decoder errors into MalformedRequest errors.
+func decodeJSON(body io.Reader, dst any, opts ...DecodeOption) error {
+	o := decodeOptions{maxDepth: defaultMaxDepth}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			msg := fmt.Sprintf("Request body must not be larger than %d bytes", maxBytesError.Limit)
+			return &MalformedRequest{Status: http.StatusRequestEntityTooLarge, Msg: msg}
+		}
+		return err
+	}
+	if exceedsDepth(data, o.maxDepth) {
+		msg := fmt.Sprintf("Request body must not nest deeper than %d levels", o.maxDepth)
+		return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+	}
 
-	dec := json.NewDecoder(r.Body)
+	dec := json.NewDecoder(bytes.NewReader(data))
 	dec.DisallowUnknownFields()
+	if o.useNumber {
+		dec.UseNumber()
+	}
 
-	err := dec.Decode(&dst)
+	err = dec.Decode(&dst)
 	if err != nil {
 		var syntaxError *json.SyntaxError
 		var unmarshalTypeError *json.UnmarshalTypeError
-		var maxBytesError *http.MaxBytesError
 
 		switch {
 		case errors.As(err, &syntaxError):
@@ -63,10 +199,6 @@ func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst any) error {
 			msg := "Request body must not be empty"
 			return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
 
-		case errors.As(err, &maxBytesError):
-			msg := fmt.Sprintf("Request body must not be larger than %d bytes", maxBytesError.Limit)
-			return &MalformedRequest{Status: http.StatusRequestEntityTooLarge, Msg: msg}
-
 		default:
 			return err
 		}
@@ -81,23 +213,60 @@ func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst any) error {
 	return nil
 }
 
+// exceedsDepth reports whether arrays and objects in data nest deeper than
+// max, walking the token stream so the check itself does not recurse. Syntax
+// errors end the walk; the decode that follows reports them.
+func exceedsDepth(data []byte, max int) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > max {
+				return true
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// isJSONContentType reports whether a Content-Type header value is application/json.
+func isJSONContentType(ct string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+	return mediaType == "application/json"
+}
+
 type Response struct {
 	Status       bool   `json:"status"`
 	Code         int    `json:"code"`
 	ResponseCode int    `json:"response_code,omitempty"`
 	Message      string `json:"message,omitempty"`
 	Data         any    `json:"data,omitempty"`
+	TraceID      string `json:"trace_id,omitempty"`
 }
 
+// JSONContentLength makes JSONResponse buffer the encoded body so it can set
+// Content-Length. Disable it to stream very large payloads without buffering.
+var JSONContentLength = true
+
 func JSONResponse(w http.ResponseWriter, message string, data any, statusCodes ...int) {
-	successStatusCodes := []int{200, 201, 202, 203, 204, 205, 206, 207, 208, 226}
-	status := true
+	writeResponse(w, newResponse(message, data, statusCodes...))
+}
+
+// newResponse builds the envelope written by JSONResponse.
+func newResponse(message string, data any, statusCodes ...int) Response {
 	code := 200
 	if len(statusCodes) > 0 {
 		code = statusCodes[0]
 	}
 
-	status = statusCodePresent(code, successStatusCodes)
+	status := statusClassifier(code)
 
 	response := Response{
 		Status:  status,
@@ -114,22 +283,187 @@ func JSONResponse(w http.ResponseWriter, message string, data any, statusCodes .
 	if data != nil {
 		response.Data = data
 	}
+	return response
+}
 
+// writeResponse encodes response as the body, with response.Code as status.
+func writeResponse(w http.ResponseWriter, response Response) {
+	code := response.Code
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	err := json.NewEncoder(w).Encode(response)
-	if err != nil {
+
+	if !JSONContentLength {
+		w.WriteHeader(code)
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeWithLength(w, code, buf.Bytes())
+}
+
+// JSONRaw writes v as the JSON response body without the Response envelope,
+// for APIs that return bare objects. Like JSONResponse it sets Content-Length
+// unless JSONContentLength is disabled.
+func JSONRaw(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !JSONContentLength {
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	writeWithLength(w, status, buf.Bytes())
 }
 
-// Function to check if an code is present in a slice of status codes
-func statusCodePresent(statusCode int, statusCodes []int) bool {
-	for _, code := range statusCodes {
-		if code == statusCode {
-			return true
+// RenderTemplate renders the named template and writes it as an HTML response.
+// The first optional status code overrides the default 200. Pages up to
+// 64 KiB are sent with Content-Length; larger output from a *rumtpl.Manager
+// is streamed instead of being held in memory.
+func RenderTemplate(w http.ResponseWriter, renderer rumtpl.Renderer, name rumtpl.Name, data any, statusCodes ...int) {
+	code := http.StatusOK
+	if len(statusCodes) > 0 {
+		code = statusCodes[0]
+	}
+
+	if err := renderHTML(w, renderer, name, data, code); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// PageHandler serves the named template as an HTML page, rendering it on each
+// request with the data returned by data (nil data if data is nil). Render
+// errors produce a 500 JSON response unless output was already streamed, as
+// for RenderTemplate.
+func PageHandler(m rumtpl.Renderer, name rumtpl.Name, data func(*http.Request) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var pageData any
+		if data != nil {
+			pageData = data(r)
+		}
+
+		if err := renderHTML(w, m, name, pageData, http.StatusOK); err != nil {
+			JSONResponse(w, http.StatusText(http.StatusInternalServerError), nil, http.StatusInternalServerError)
 		}
 	}
+}
+
+// renderBufferSize is how much HTML output renderHTML buffers before it
+// starts streaming; smaller pages are sent whole with Content-Length.
+const renderBufferSize = 64 << 10
+
+// renderHTML writes the named template as an HTML response with status code.
+// Renderers implementing rumtpl.StreamRenderer render through a buffer of
+// renderBufferSize, so output of any size never sits in memory whole. It
+// returns the render error only while nothing has been sent, so the caller
+// can still write an error response; later errors are logged.
+func renderHTML(w http.ResponseWriter, renderer rumtpl.Renderer, name rumtpl.Name, data any, code int) error {
+	sr, ok := renderer.(rumtpl.StreamRenderer)
+	if !ok {
+		body, err := renderer.Render(name, data)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writeWithLength(w, code, body)
+		return nil
+	}
+
+	rw := &renderWriter{w: w, code: code}
+	err := sr.RenderTo(rw, name, data)
+	if rw.streaming {
+		if err != nil {
+			log.Printf("rum: rendering %s failed after the response started: %v", name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writeWithLength(w, code, rw.buf.Bytes())
+	return nil
+}
+
+// renderWriter buffers up to renderBufferSize bytes of HTML and switches to
+// writing through to w, headers first, once the output grows beyond that.
+type renderWriter struct {
+	w         http.ResponseWriter
+	code      int
+	buf       bytes.Buffer
+	streaming bool
+}
+
+func (rw *renderWriter) Write(p []byte) (int, error) {
+	if !rw.streaming {
+		if rw.buf.Len()+len(p) <= renderBufferSize {
+			return rw.buf.Write(p)
+		}
+		rw.streaming = true
+		rw.w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		rw.w.WriteHeader(rw.code)
+		if _, err := rw.w.Write(rw.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		rw.buf = bytes.Buffer{}
+	}
+	return rw.w.Write(p)
+}
+
+// writeWithLength writes a fully buffered body with its Content-Length set.
+// Statuses that must not carry a body (1xx, 204 and 304) are sent without
+// either.
+func writeWithLength(w http.ResponseWriter, code int, body []byte) {
+	if !bodyAllowed(code) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(code)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// bodyAllowed reports whether a response with the given status may include a
+// body, following RFC 9110.
+func bodyAllowed(code int) bool {
+	switch {
+	case code >= 100 && code < 200:
+		return false
+	case code == http.StatusNoContent, code == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// statusClassifier decides the Status field of JSONResponse envelopes.
+var statusClassifier = isSuccessStatus
+
+// SetStatusClassifier overrides how JSONResponse maps a status code to the
+// envelope's Status field. Passing nil restores the default (any 2xx code).
+// It is meant to be called once during application setup.
+func SetStatusClassifier(fn func(code int) bool) {
+	if fn == nil {
+		fn = isSuccessStatus
+	}
+	statusClassifier = fn
+}
 
-	return false
+// isSuccessStatus reports whether code is in the 2xx range.
+func isSuccessStatus(code int) bool {
+	return code >= 200 && code < 300
 }
@@ -0,0 +1,381 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	rumtpl "github.com/4Sigma/rum/template_manager"
+)
+
+func TestJSONResponseContentLength(t *testing.T) {
+	rec := httptest.NewRecorder()
+	JSONResponse(rec, "ok", map[string]string{"id": "42"})
+
+	want := strconv.Itoa(rec.Body.Len())
+	if got := rec.Header().Get("Content-Length"); got != want {
+		t.Errorf("Content-Length = %q, want %q", got, want)
+	}
+}
+
+func TestJSONResponseWithoutContentLength(t *testing.T) {
+	JSONContentLength = false
+	defer func() { JSONContentLength = true }()
+
+	rec := httptest.NewRecorder()
+	JSONResponse(rec, "ok", nil, http.StatusCreated)
+
+	if got := rec.Header().Get("Content-Length"); got != "" {
+		t.Errorf("expected no Content-Length, got %q", got)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+}
+
+func TestJSONResponseNoBodyStatus(t *testing.T) {
+	for _, code := range []int{http.StatusNoContent, http.StatusNotModified} {
+		rec := httptest.NewRecorder()
+		JSONResponse(rec, "ok", nil, code)
+
+		if rec.Code != code {
+			t.Errorf("status = %d, want %d", rec.Code, code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("%d: body = %q, want empty", code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Length"); got != "" {
+			t.Errorf("%d: Content-Length = %q, want none", code, got)
+		}
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	m, err := rumtpl.NewManagerFromFS(fstest.MapFS{
+		"home.html.tmpl": {Data: []byte("<h1>{{.}}</h1>")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	RenderTemplate(rec, m, "home.html.tmpl", "Hello")
+
+	if rec.Body.String() != "<h1>Hello</h1>" {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Length"); got != "14" {
+		t.Errorf("Content-Length = %q, want %q", got, "14")
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestRenderTemplateStreamsLargeOutput(t *testing.T) {
+	m, err := rumtpl.NewManagerFromFS(fstest.MapFS{
+		"report.html.tmpl": {Data: []byte(`{{range .}}<p>row</p>{{end}}`)},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	rows := make([]struct{}, 2*renderBufferSize/len("<p>row</p>"))
+	rec := httptest.NewRecorder()
+	RenderTemplate(rec, m, "report.html.tmpl", rows, http.StatusAccepted)
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	if got, want := rec.Body.Len(), len(rows)*len("<p>row</p>"); got != want {
+		t.Errorf("body is %d bytes, want %d", got, want)
+	}
+	if got := rec.Header().Get("Content-Length"); got != "" {
+		t.Errorf("streamed output should not set Content-Length, got %q", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestJSONResponseStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       int
+		classifier func(int) bool
+		want       bool
+	}{
+		{"accepted", http.StatusAccepted, nil, true},
+		{"redirect", http.StatusMultipleChoices, nil, false},
+		{"custom classifier", http.StatusFound, func(code int) bool {
+			return code < 400
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetStatusClassifier(tt.classifier)
+			defer SetStatusClassifier(nil)
+
+			rec := httptest.NewRecorder()
+			JSONResponse(rec, "", nil, tt.code)
+
+			var resp Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.Status != tt.want {
+				t.Errorf("Status = %v, want %v", resp.Status, tt.want)
+			}
+			if resp.Code != tt.code {
+				t.Errorf("Code = %d, want %d", resp.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestWriteMalformed(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    int
+		wantMessage string
+	}{
+		{
+			name:        "malformed request",
+			err:         &MalformedRequest{Status: http.StatusBadRequest, Msg: "Request body must not be empty"},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Request body must not be empty",
+		},
+		{
+			name:        "wrapped malformed request",
+			err:         fmt.Errorf("decoding: %w", &MalformedRequest{Status: http.StatusUnsupportedMediaType, Msg: "bad type"}),
+			wantCode:    http.StatusUnsupportedMediaType,
+			wantMessage: "bad type",
+		},
+		{
+			name:        "generic error",
+			err:         errors.New("database is down"),
+			wantCode:    http.StatusInternalServerError,
+			wantMessage: "Internal Server Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			WriteMalformed(rec, tt.err)
+
+			var resp Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if rec.Code != tt.wantCode || resp.Code != tt.wantCode {
+				t.Errorf("status = %d (envelope %d), want %d", rec.Code, resp.Code, tt.wantCode)
+			}
+			if resp.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", resp.Message, tt.wantMessage)
+			}
+			if resp.Status {
+				t.Error("expected Status false")
+			}
+		})
+	}
+}
+
+func TestMultiMalformed(t *testing.T) {
+	if MultiMalformed(nil, nil) != nil {
+		t.Error("expected nil when all errors are nil")
+	}
+
+	err := MultiMalformed(
+		&MalformedRequest{Status: http.StatusBadRequest, Msg: "page must be a positive integer"},
+		nil,
+		fmt.Errorf("body: %w", &MalformedRequest{Status: http.StatusUnsupportedMediaType, Msg: "Content-Type header is not application/json"}),
+	)
+	if err.Status != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want %d", err.Status, http.StatusUnsupportedMediaType)
+	}
+	wantMsg := "page must be a positive integer; Content-Type header is not application/json"
+	if err.Msg != wantMsg {
+		t.Errorf("message = %q, want %q", err.Msg, wantMsg)
+	}
+
+	rec := httptest.NewRecorder()
+	WriteMalformed(rec, err)
+
+	var resp struct {
+		Code    int                `json:"code"`
+		Message string             `json:"message"`
+		Data    []MalformedRequest `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if rec.Code != http.StatusUnsupportedMediaType || resp.Message != wantMsg {
+		t.Errorf("response = %d %q", rec.Code, resp.Message)
+	}
+	if len(resp.Data) != 2 || resp.Data[0].Status != http.StatusBadRequest || resp.Data[1].Status != http.StatusUnsupportedMediaType {
+		t.Errorf("data = %+v, want both errors", resp.Data)
+	}
+}
+
+func TestDecodeJSONBodyRaw(t *testing.T) {
+	body := `{"name": "Ada",  "age": 36}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	var dst struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	raw, err := DecodeJSONBodyRaw(httptest.NewRecorder(), req, &dst)
+	if err != nil {
+		t.Fatalf("DecodeJSONBodyRaw error: %v", err)
+	}
+
+	if string(raw) != body {
+		t.Errorf("raw = %q, want %q", raw, body)
+	}
+	if dst.Name != "Ada" || dst.Age != 36 {
+		t.Errorf("decoded = %+v", dst)
+	}
+}
+
+func TestDecodeJSONBodyUseNumber(t *testing.T) {
+	const id = "9007199254740993" // 2^53 + 1, not representable as float64
+
+	decode := func(opts ...DecodeOption) any {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id": `+id+`}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		var dst map[string]any
+		if err := DecodeJSONBody(httptest.NewRecorder(), req, &dst, opts...); err != nil {
+			t.Fatalf("DecodeJSONBody error: %v", err)
+		}
+		return dst["id"]
+	}
+
+	got, ok := decode(WithUseNumber()).(json.Number)
+	if !ok {
+		t.Fatalf("expected json.Number, got %T", got)
+	}
+	if n, err := got.Int64(); err != nil || strconv.FormatInt(n, 10) != id {
+		t.Errorf("id = %v (%v), want %s", n, err, id)
+	}
+
+	if f, ok := decode().(float64); !ok || strconv.FormatFloat(f, 'f', 0, 64) == id {
+		t.Errorf("default decoding should keep float64, got %T %v", decode(), decode())
+	}
+}
+
+func TestDecodeJSONBodyRawKeepsValidation(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"unknown": 1}`))
+
+	var dst struct {
+		Name string `json:"name"`
+	}
+	raw, err := DecodeJSONBodyRaw(httptest.NewRecorder(), req, &dst)
+
+	var mr *MalformedRequest
+	if !errors.As(err, &mr) || mr.Status != http.StatusBadRequest {
+		t.Fatalf("expected 400 MalformedRequest, got %v", err)
+	}
+	if string(raw) != `{"unknown": 1}` {
+		t.Errorf("raw = %q", raw)
+	}
+}
+
+func TestDecodeJSONBodyMaxDepth(t *testing.T) {
+	nested := func(depth int) string {
+		return `{"v":` + strings.Repeat("[", depth-1) + strings.Repeat("]", depth-1) + "}"
+	}
+	decode := func(body string, opts ...DecodeOption) error {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		var dst map[string]any
+		return DecodeJSONBody(httptest.NewRecorder(), req, &dst, opts...)
+	}
+
+	if err := decode(nested(64)); err != nil {
+		t.Errorf("depth 64 should decode with the default limit, got %v", err)
+	}
+
+	var mr *MalformedRequest
+	err := decode(nested(10000))
+	if !errors.As(err, &mr) || mr.Status != http.StatusBadRequest || !strings.Contains(mr.Msg, "64") {
+		t.Errorf("expected 400 for depth 10000, got %v", err)
+	}
+
+	err = decode(nested(5), WithMaxDepth(4))
+	if !errors.As(err, &mr) || mr.Status != http.StatusBadRequest {
+		t.Errorf("expected 400 with WithMaxDepth(4), got %v", err)
+	}
+	if err := decode(nested(4), WithMaxDepth(4)); err != nil {
+		t.Errorf("depth 4 should decode with WithMaxDepth(4), got %v", err)
+	}
+}
+
+func TestPageHandler(t *testing.T) {
+	m, err := rumtpl.NewManagerFromFS(fstest.MapFS{
+		"page.html.tmpl":   {Data: []byte("<p>{{.Path}}</p>")},
+		"broken.html.tmpl": {Data: []byte("{{.Missing.Field}}")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	PageHandler(m, "page.html.tmpl", func(r *http.Request) any { return TemplateContext(r) }).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/about", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Body.String() != "<p>/about</p>" {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	PageHandler(m, "broken.html.tmpl", func(*http.Request) any { return map[string]any{"Missing": 1} }).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+}
+
+func TestJSONRaw(t *testing.T) {
+	rec := httptest.NewRecorder()
+	JSONRaw(rec, http.StatusCreated, map[string]any{"id": 7, "name": "rum"})
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(body) != 2 || body["name"] != "rum" || body["id"] != float64(7) {
+		t.Errorf("body = %v, want the raw object", body)
+	}
+	for _, key := range []string{"status", "code", "message", "data"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("unexpected envelope key %q", key)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client's key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// CachedResponse is a recorded response replayed for a repeated key.
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// RequestHash is the hex SHA-256 of the request body the response was
+	// recorded for.
+	RequestHash string
+}
+
+// IdempotencyStore persists responses by idempotency key.
+type IdempotencyStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore whose entries
+// expire after a TTL.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]memoryIdempotencyEntry
+}
+
+type memoryIdempotencyEntry struct {
+	resp      *CachedResponse
+	expiresAt time.Time
+}
+
+// NewMemoryIdempotencyStore creates an in-memory store keeping responses for ttl.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{ttl: ttl, entries: make(map[string]memoryIdempotencyEntry)}
+}
+
+// Get implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return entry.resp, true
+}
+
+// Set implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Set(key string, resp *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = memoryIdempotencyEntry{resp: resp, expiresAt: now.Add(s.ttl)}
+}
+
+// IdempotencyOption configures the Idempotency middleware.
+type IdempotencyOption func(*idempotencyOptions)
+
+type idempotencyOptions struct {
+	caller func(*http.Request) string
+}
+
+// IdempotencyCaller sets how the caller of a request is identified, e.g. by
+// the user ID of a session. Keys are scoped to the caller, so one client can
+// never replay another's response. The default is the Authorization header.
+func IdempotencyCaller(fn func(r *http.Request) string) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.caller = fn
+	}
+}
+
+// authorizationCaller is the default caller identity of Idempotency.
+func authorizationCaller(r *http.Request) string {
+	return r.Header.Get("Authorization")
+}
+
+// Idempotency replays the stored response when a POST, PUT, PATCH or DELETE
+// request repeats an Idempotency-Key, without invoking the handler again.
+// Keys are scoped to the caller (see IdempotencyCaller), method and path.
+// Reusing a key with a different request body is rejected with 422, and a
+// request arriving while another with the same key is still running gets
+// 409, so the handler never runs twice for one key within this process.
+// 5xx responses are not stored so the client can retry them. Only headers
+// set by the handler are stored and replayed.
+func Idempotency(store IdempotencyStore, opts ...IdempotencyOption) func(http.Handler) http.Handler {
+	o := idempotencyOptions{caller: authorizationCaller}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.caller == nil {
+		o.caller = authorizationCaller
+	}
+
+	var (
+		mu       sync.Mutex
+		inFlight = make(map[string]bool)
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			switch {
+			case key == "":
+				next.ServeHTTP(w, r)
+				return
+			case r.Method != http.MethodPost && r.Method != http.MethodPut &&
+				r.Method != http.MethodPatch && r.Method != http.MethodDelete:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				JSONResponse(w, "failed to read request body", nil, http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			bodyHash := sha256.Sum256(body)
+			requestHash := hex.EncodeToString(bodyHash[:])
+
+			caller := sha256.Sum256([]byte(o.caller(r)))
+			storeKey := hex.EncodeToString(caller[:]) + " " + r.Method + " " + r.URL.Path + " " + key
+
+			mu.Lock()
+			if inFlight[storeKey] {
+				mu.Unlock()
+				JSONResponse(w, "a request with this Idempotency-Key is already in progress", nil, http.StatusConflict)
+				return
+			}
+			cached, ok := store.Get(storeKey)
+			if !ok {
+				inFlight[storeKey] = true
+			}
+			mu.Unlock()
+
+			if ok {
+				if cached.RequestHash != requestHash {
+					JSONResponse(w, "Idempotency-Key was already used with a different request body", nil, http.StatusUnprocessableEntity)
+					return
+				}
+				for k, v := range cached.Header {
+					w.Header()[k] = v
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(cached.Status)
+				w.Write(cached.Body)
+				return
+			}
+
+			defer func() {
+				mu.Lock()
+				delete(inFlight, storeKey)
+				mu.Unlock()
+			}()
+
+			rec := &responseCapture{ResponseWriter: w, status: http.StatusOK, before: w.Header().Clone()}
+			next.ServeHTTP(rec, r)
+
+			if rec.status < http.StatusInternalServerError {
+				store.Set(storeKey, &CachedResponse{
+					Status:      rec.status,
+					Header:      rec.handlerHeader(),
+					Body:        rec.body.Bytes(),
+					RequestHash: requestHash,
+				})
+			}
+		})
+	}
+}
+
+// responseCapture passes writes through while recording status, body and
+// the headers the handler set.
+type responseCapture struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+
+	before http.Header // headers present before the handler ran
+	header http.Header // headers set by the handler, taken when it started the response
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	if !c.wroteHeader {
+		c.status = code
+		c.start()
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		c.start()
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// start records the handler's headers before outer writers add their own
+// while the response starts.
+func (c *responseCapture) start() {
+	c.wroteHeader = true
+	c.header = make(http.Header)
+	for k, v := range c.Header() {
+		if !slices.Equal(c.before[k], v) {
+			c.header[k] = slices.Clone(v)
+		}
+	}
+}
+
+// handlerHeader returns the headers the handler set.
+func (c *responseCapture) handlerHeader() http.Header {
+	if !c.wroteHeader {
+		c.start()
+	}
+	return c.header
+}
+
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
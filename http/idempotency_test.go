@@ -0,0 +1,163 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		JSONResponse(w, "created", map[string]int{"order": calls}, http.StatusCreated)
+	}))
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send("abc")
+	second := send("abc")
+
+	if calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+	if second.Code != http.StatusCreated {
+		t.Errorf("replayed status = %d, want %d", second.Code, http.StatusCreated)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("replayed body = %q, want %q", second.Body.String(), first.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("expected replay marker header")
+	}
+	if second.Header().Get("Content-Type") != "application/json" {
+		t.Error("expected original headers to be replayed")
+	}
+
+	send("other")
+	send("")
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func(auth, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	send("Bearer alice", `{"qty":1}`)
+	if code := send("Bearer bob", `{"qty":1}`); code != http.StatusCreated || calls != 2 {
+		t.Errorf("another caller's key was replayed: status %d, %d calls", code, calls)
+	}
+	if code := send("Bearer alice", `{"qty":2}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with another body: status = %d, want 422", code)
+	}
+	if code := send("Bearer alice", `{"qty":1}`); code != http.StatusCreated || calls != 2 {
+		t.Errorf("same request was not replayed: status %d, %d calls", code, calls)
+	}
+}
+
+func TestIdempotencyConcurrent(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		return req
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest())
+	if rec.Code != http.StatusConflict {
+		t.Errorf("concurrent request: status = %d, want 409", rec.Code)
+	}
+
+	close(release)
+	<-done
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest())
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("after completion: status = %d, want a replayed 201", rec.Code)
+	}
+}
+
+func TestIdempotencyReplaysHandlerHeadersOnly(t *testing.T) {
+	requestID := 0
+	outer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID++
+			w.Header().Set("X-Request-Id", string(rune('0'+requestID)))
+			next.ServeHTTP(w, r)
+		})
+	}
+	store := NewMemoryIdempotencyStore(time.Minute)
+	handler := Chain(outer, ServerTiming, Idempotency(store))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	send()
+	replay := send()
+	if got := replay.Header().Get("Location"); got != "/orders/1" {
+		t.Errorf("Location = %q, want the handler's header replayed", got)
+	}
+	if got := replay.Header().Get("X-Request-Id"); got != "2" {
+		t.Errorf("X-Request-Id = %q, want the new request's ID", got)
+	}
+	for _, entry := range store.entries {
+		if len(entry.resp.Header) != 1 {
+			t.Errorf("stored headers = %v, want only Location", entry.resp.Header)
+		}
+	}
+}
+
+func TestMemoryIdempotencyStoreExpires(t *testing.T) {
+	store := NewMemoryIdempotencyStore(-time.Second)
+	store.Set("key", &CachedResponse{Status: http.StatusOK})
+
+	if _, ok := store.Get("key"); ok {
+		t.Error("expected expired entry to be dropped")
+	}
+}
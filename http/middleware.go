@@ -0,0 +1,85 @@
+package http
+
+import (
+	"log"
+	"net/http"
+)
+
+// RequireJSON rejects POST, PUT and PATCH requests whose body is not declared
+// as application/json with 415, before next runs. Other methods and requests
+// without a body pass through.
+func RequireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			if r.ContentLength != 0 && !isJSONContentType(r.Header.Get("Content-Type")) {
+				JSONResponse(w, "Content-Type header is not application/json", nil, http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Chain composes middlewares into one, applied left to right: the first
+// middleware is the outermost, so it sees the request first and the response
+// last. Chain(a, b, c)(h) is equivalent to a(b(c(h))).
+func Chain(middlewares ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// SafeResponseWriter guards against writing a response twice, e.g. when a
+// handler has already replied and a recovering middleware then writes an
+// error. Once the status is sent, a further WriteHeader is dropped with a
+// logged warning, and so is everything written after it, so a second
+// JSONResponse leaves the first response intact.
+type SafeResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	discarding  bool
+}
+
+// NewSafeResponseWriter wraps w in a SafeResponseWriter.
+func NewSafeResponseWriter(w http.ResponseWriter) *SafeResponseWriter {
+	return &SafeResponseWriter{ResponseWriter: w}
+}
+
+// WriteOnce wraps every response of next in a SafeResponseWriter.
+func WriteOnce(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(NewSafeResponseWriter(w), r)
+	})
+}
+
+// Written reports whether the response has been started.
+func (s *SafeResponseWriter) Written() bool {
+	return s.wroteHeader
+}
+
+func (s *SafeResponseWriter) WriteHeader(code int) {
+	if s.wroteHeader {
+		log.Printf("rum: ignoring WriteHeader(%d) after the response was started", code)
+		s.discarding = true
+		return
+	}
+	s.wroteHeader = true
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *SafeResponseWriter) Write(b []byte) (int, error) {
+	if s.discarding {
+		return len(b), nil
+	}
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *SafeResponseWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		wantCode    int
+	}{
+		{"json post", http.MethodPost, "application/json; charset=utf-8", `{"a":1}`, http.StatusOK},
+		{"form post", http.MethodPost, "application/x-www-form-urlencoded", "a=1", http.StatusUnsupportedMediaType},
+		{"missing content type", http.MethodPatch, "", `{"a":1}`, http.StatusUnsupportedMediaType},
+		{"get", http.MethodGet, "", "", http.StatusOK},
+	}
+
+	handler := RequireJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	record := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name+" in")
+				next.ServeHTTP(w, r)
+				calls = append(calls, name+" out")
+			})
+		}
+	}
+
+	handler := Chain(record("a"), record("b"), record("c"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := "a in,b in,c in,handler,c out,b out,a out"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+}
+
+func TestChainEmpty(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Chain()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTeapot)
+	}
+}
+
+func TestWriteOnce(t *testing.T) {
+	handler := WriteOnce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		JSONResponse(w, "created", nil, http.StatusCreated)
+		// A second response, as a recovering middleware might write.
+		JSONResponse(w, "boom", nil, http.StatusInternalServerError)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	var resp Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("body is not a single JSON response %q: %v", rec.Body.String(), err)
+	}
+	if resp.Message != "created" {
+		t.Errorf("message = %q, want %q", resp.Message, "created")
+	}
+}
+
+func TestSafeResponseWriterStreaming(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewSafeResponseWriter(rec)
+
+	if w.Written() {
+		t.Error("Written() before any write")
+	}
+	w.Write([]byte("a"))
+	w.Write([]byte("b"))
+	if !w.Written() || rec.Body.String() != "ab" {
+		t.Errorf("body = %q, want repeated writes to pass through", rec.Body.String())
+	}
+}
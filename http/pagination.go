@@ -0,0 +1,39 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ParsePagination reads the page and per_page query parameters of r. A
+// missing page is 1 and a missing per_page is defaultPerPage; per_page is
+// clamped to maxPerPage. Values that are not positive integers are reported
+// as a 400 *MalformedRequest.
+func ParsePagination(r *http.Request, defaultPerPage, maxPerPage int) (page, perPage int, err error) {
+	query := r.URL.Query()
+
+	page, err = positiveQueryInt(query.Get("page"), "page", 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	perPage, err = positiveQueryInt(query.Get("per_page"), "per_page", defaultPerPage)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return page, min(perPage, maxPerPage), nil
+}
+
+// positiveQueryInt parses a query parameter value, returning def when empty.
+func positiveQueryInt(value, name string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		msg := fmt.Sprintf("Query parameter %q must be a positive integer", name)
+		return 0, &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+	}
+	return n, nil
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantPage    int
+		wantPerPage int
+		wantErr     bool
+	}{
+		{"defaults", "", 1, 20, false},
+		{"explicit", "?page=3&per_page=50", 3, 50, false},
+		{"clamped to max", "?per_page=1000", 1, 100, false},
+		{"non-numeric page", "?page=two", 0, 0, true},
+		{"negative per_page", "?per_page=-5", 0, 0, true},
+		{"zero page", "?page=0", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/items"+tt.query, nil)
+			page, perPage, err := ParsePagination(req, 20, 100)
+
+			if tt.wantErr {
+				var mr *MalformedRequest
+				if !errors.As(err, &mr) || mr.Status != http.StatusBadRequest {
+					t.Fatalf("expected 400 MalformedRequest, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if page != tt.wantPage || perPage != tt.wantPerPage {
+				t.Errorf("got page %d per_page %d, want %d and %d", page, perPage, tt.wantPage, tt.wantPerPage)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var userSchema = []byte(`{
+	"type": "object",
+	"required": ["name", "age"],
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"age":  {"type": "integer", "minimum": 0},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "uniqueItems": true}
+	}
+}`)
+
+type schemaUser struct {
+	Name string   `json:"name"`
+	Age  int      `json:"age"`
+	Role string   `json:"role"`
+	Tags []string `json:"tags"`
+}
+
+func TestDecodeJSONBodySchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantMsg    []string
+	}{
+		{
+			name: "valid",
+			body: `{"name":"Ada","age":36,"role":"admin"}`,
+		},
+		{
+			name:       "missing required",
+			body:       `{"name":"Ada"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantMsg:    []string{`/: missing property 'age'`},
+		},
+		{
+			name:       "wrong type and enum",
+			body:       `{"name":"Ada","age":1.5,"role":"root"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantMsg:    []string{"/age: got number, want integer", "/role: value must be one of"},
+		},
+		{
+			name:       "keyword beyond the old subset",
+			body:       `{"name":"Ada","age":36,"tags":["a","a"]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantMsg:    []string{"/tags: items at 0 and 1 are equal"},
+		},
+		{
+			name:       "bad json",
+			body:       `{"name":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			var dst schemaUser
+			err := DecodeJSONBodySchema(httptest.NewRecorder(), req, &dst, userSchema)
+
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if want := (schemaUser{Name: "Ada", Age: 36, Role: "admin"}); !reflect.DeepEqual(dst, want) {
+					t.Errorf("decoded = %+v", dst)
+				}
+				return
+			}
+
+			var mr *MalformedRequest
+			if !errors.As(err, &mr) {
+				t.Fatalf("expected *MalformedRequest, got %v", err)
+			}
+			if mr.Status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", mr.Status, tt.wantStatus)
+			}
+			for _, want := range tt.wantMsg {
+				if !strings.Contains(mr.Msg, want) {
+					t.Errorf("message %q does not contain %q", mr.Msg, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeJSONBodySchemaInvalidSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantMsg string
+	}{
+		{"not json", `{"type":`, "invalid JSON schema"},
+		{"bad type", `{"type": 1}`, "invalid JSON schema"},
+		{"bad pattern", `{"type": "string", "pattern": "("}`, "invalid JSON schema"},
+		{"dangling ref", `{"items": {"$ref": "#/defs/x"}}`, "invalid JSON schema"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+
+			var dst schemaUser
+			err := DecodeJSONBodySchema(httptest.NewRecorder(), req, &dst, []byte(tt.schema))
+
+			var mr *MalformedRequest
+			if err == nil || errors.As(err, &mr) {
+				t.Fatalf("expected plain schema error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("error = %q, want it to mention %s", err, tt.wantMsg)
+			}
+		})
+	}
+}
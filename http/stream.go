@@ -0,0 +1,146 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"log"
+	"net/http"
+)
+
+var (
+	ErrStreamTruncated = errors.New("stream stopped at the size limit")
+)
+
+// JSONStreamOptions configures JSONStream. A nil *JSONStreamOptions uses defaults.
+type JSONStreamOptions struct {
+	// FlushEvery flushes the response after every N elements so clients
+	// receive data incrementally. Zero or negative means every element.
+	FlushEvery int
+	// MaxBytes caps the size of the response body. The element that would
+	// exceed it is not written; the array is closed instead, so the client
+	// gets valid JSON holding the elements sent so far. Zero means no limit.
+	MaxBytes int64
+}
+
+func (o *JSONStreamOptions) flushEvery() int {
+	if o == nil || o.FlushEvery <= 0 {
+		return 1
+	}
+	return o.FlushEvery
+}
+
+func (o *JSONStreamOptions) maxBytes() int64 {
+	if o == nil {
+		return 0
+	}
+	return o.MaxBytes
+}
+
+// JSONStream writes items as a JSON array without buffering the whole
+// response, flushing as configured by opts and once more at the end. The
+// status is always 200; since headers are sent before the first element, an
+// encoding error mid-stream cannot be reported to the client and is returned
+// to the caller instead, leaving the array unterminated. Reaching
+// opts.MaxBytes ends the array early, logs, and returns ErrStreamTruncated.
+func JSONStream[T any](w http.ResponseWriter, items iter.Seq[T], opts *JSONStreamOptions) error {
+	flushEvery := opts.flushEvery()
+	maxBytes := opts.maxBytes()
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+
+	count := 0
+	written := int64(len("[") + len("]\n"))
+	for item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("encoding element %d: %w", count, err)
+		}
+		if count > 0 {
+			written++ // comma
+		}
+		written += int64(len(b))
+		if maxBytes > 0 && written > maxBytes {
+			log.Printf("rum: JSON stream truncated after %d elements at %d bytes", count, maxBytes)
+			if _, err := w.Write([]byte("]\n")); err != nil {
+				return err
+			}
+			rc.Flush()
+			return fmt.Errorf("%w: %d elements sent", ErrStreamTruncated, count)
+		}
+		if count > 0 {
+			if _, err := w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+
+		count++
+		if count%flushEvery == 0 {
+			rc.Flush()
+		}
+	}
+
+	if _, err := w.Write([]byte("]\n")); err != nil {
+		return err
+	}
+	rc.Flush()
+	return nil
+}
+
+// csvFlushRows is how many CSV rows CSVStream writes between flushes.
+const csvFlushRows = 100
+
+// CSVStream sends header and then every row received from rows as a CSV
+// attachment named filename, until rows is closed. Fields are quoted by
+// encoding/csv as needed and the response is flushed every csvFlushRows rows
+// and at the end. A nil header writes no header line. As with JSONStream, a
+// write error mid-stream is returned to the caller; the producer should stop
+// sending on rows once CSVStream has returned.
+func CSVStream(w http.ResponseWriter, header []string, rows <-chan []string, filename string) error {
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", contentDisposition(filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	if header != nil {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+
+	count := 0
+	for row := range rows {
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("writing row %d: %w", count, err)
+		}
+
+		count++
+		if count%csvFlushRows == 0 {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			rc.Flush()
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	rc.Flush()
+	return nil
+}
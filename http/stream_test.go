@@ -0,0 +1,129 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// flushRecorder records the body written so far at every Flush call.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes []string
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes = append(f.flushes, f.Body.String())
+	f.ResponseRecorder.Flush()
+}
+
+func TestJSONStream(t *testing.T) {
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	err := JSONStream(rec, slices.Values([]int{1, 2, 3, 4, 5}), &JSONStreamOptions{FlushEvery: 2})
+	if err != nil {
+		t.Fatalf("JSONStream error: %v", err)
+	}
+
+	var got []int
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	if !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("body = %v", got)
+	}
+
+	want := []string{"[1,2", "[1,2,3,4", "[1,2,3,4,5]\n"}
+	if !slices.Equal(rec.flushes, want) {
+		t.Errorf("flushes = %q, want %q", rec.flushes, want)
+	}
+}
+
+func TestJSONStreamEmpty(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	if err := JSONStream(rec, slices.Values([]string{}), nil); err != nil {
+		t.Fatalf("JSONStream error: %v", err)
+	}
+	if rec.Body.String() != "[]\n" {
+		t.Errorf("body = %q, want %q", rec.Body.String(), "[]\n")
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestJSONStreamMaxBytes(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	// Every element is 5 bytes; "[" + 3 elements + 2 commas + "]\n" is 20.
+	items := func(yield func(string) bool) {
+		for {
+			if !yield("abc") {
+				return
+			}
+		}
+	}
+	err := JSONStream(rec, items, &JSONStreamOptions{MaxBytes: 22})
+	if !errors.Is(err, ErrStreamTruncated) {
+		t.Fatalf("expected ErrStreamTruncated, got %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+	if rec.Body.Len() > 22 {
+		t.Errorf("body is %d bytes, over the 22 byte limit", rec.Body.Len())
+	}
+
+	var got []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("truncated output is not valid JSON %q: %v", rec.Body.String(), err)
+	}
+	if len(got) != 3 {
+		t.Errorf("got %d elements, want 3", len(got))
+	}
+}
+
+func TestCSVStream(t *testing.T) {
+	rows := make(chan []string)
+	go func() {
+		defer close(rows)
+		rows <- []string{"1", "Ada Lovelace", "ada@example.com"}
+		rows <- []string{"2", `Grace "Amazing" Hopper`, "grace@example.com"}
+		rows <- []string{"3", "Smith, John", "line\nbreak"}
+	}()
+
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	header := []string{"id", "name", "email"}
+	if err := CSVStream(rec, header, rows, "users.csv"); err != nil {
+		t.Fatalf("CSVStream error: %v", err)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != "attachment; filename=users.csv" {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if len(rec.flushes) == 0 {
+		t.Error("expected the response to be flushed")
+	}
+
+	got, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV %q: %v", rec.Body.String(), err)
+	}
+	want := [][]string{
+		header,
+		{"1", "Ada Lovelace", "ada@example.com"},
+		{"2", `Grace "Amazing" Hopper`, "grace@example.com"},
+		{"3", "Smith, John", "line\nbreak"},
+	}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+}
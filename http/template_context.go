@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+)
+
+// TemplateContextFunc adds request-derived values, such as a CSRF token or
+// flash messages, to the base template data.
+type TemplateContextFunc func(r *http.Request, data map[string]any)
+
+var (
+	templateContextMu    sync.RWMutex
+	templateContextFuncs []TemplateContextFunc
+)
+
+// AddTemplateContextFunc registers fn to run on every TemplateContext call,
+// after the built-in values are set. It is meant to be called during
+// application setup.
+func AddTemplateContextFunc(fn TemplateContextFunc) {
+	templateContextMu.Lock()
+	defer templateContextMu.Unlock()
+	templateContextFuncs = append(templateContextFuncs, fn)
+}
+
+// TemplateContext assembles the base data map for rendering a page for r:
+//
+//	Method  request method
+//	Path    URL path
+//	Query   parsed query string (url.Values)
+//	Host    request host
+//
+// followed by the values of every registered TemplateContextFunc. Handlers
+// add page-specific keys to the returned map before calling RenderTemplate.
+func TemplateContext(r *http.Request) map[string]any {
+	data := map[string]any{
+		"Method": r.Method,
+		"Path":   r.URL.Path,
+		"Query":  r.URL.Query(),
+		"Host":   r.Host,
+	}
+
+	templateContextMu.RLock()
+	defer templateContextMu.RUnlock()
+	for _, fn := range templateContextFuncs {
+		fn(r, data)
+	}
+	return data
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestTemplateContext(t *testing.T) {
+	defer func() { templateContextFuncs = nil }()
+	AddTemplateContextFunc(func(r *http.Request, data map[string]any) {
+		data["CSRFToken"] = r.Header.Get("X-CSRF-Token")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders/42?tab=items", nil)
+	req.Header.Set("X-CSRF-Token", "tok")
+
+	data := TemplateContext(req)
+
+	if data["Method"] != http.MethodPost {
+		t.Errorf("Method = %v, want %q", data["Method"], http.MethodPost)
+	}
+	if data["Path"] != "/orders/42" {
+		t.Errorf("Path = %v, want %q", data["Path"], "/orders/42")
+	}
+	if q, ok := data["Query"].(url.Values); !ok || q.Get("tab") != "items" {
+		t.Errorf("Query = %v", data["Query"])
+	}
+	if data["CSRFToken"] != "tok" {
+		t.Errorf("CSRFToken = %v, want %q", data["CSRFToken"], "tok")
+	}
+}
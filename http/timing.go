@@ -0,0 +1,118 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+type serverTimingKey struct{}
+
+// serverTimings collects the named durations reported for one request.
+type serverTimings struct {
+	mu      sync.Mutex
+	entries []serverTimingEntry
+	// sent is set once the header has been written; later entries are dropped.
+	sent bool
+}
+
+type serverTimingEntry struct {
+	name string
+	dur  time.Duration
+}
+
+// ServerTiming measures how long next takes and reports it in a
+// "Server-Timing: total;dur=<ms>" header, together with any sub-timings added
+// through AddServerTiming or StartServerTiming. Headers cannot change once the
+// response has started, so the total covers the handler up to its first
+// WriteHeader or Write call, or its whole run if it writes nothing.
+func ServerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timings := &serverTimings{}
+		tw := &timingWriter{ResponseWriter: w, start: time.Now(), timings: timings}
+
+		next.ServeHTTP(tw, r.WithContext(context.WithValue(r.Context(), serverTimingKey{}, timings)))
+		tw.setHeader()
+	})
+}
+
+// AddServerTiming records a named duration, such as "db" or "render", for the
+// Server-Timing header of the request ctx belongs to. It is a no-op outside
+// the ServerTiming middleware or once the response has started. name must be
+// an HTTP token (RFC 7230), since it is written into the header unquoted;
+// other names are dropped with a logged warning.
+func AddServerTiming(ctx context.Context, name string, dur time.Duration) {
+	timings, ok := ctx.Value(serverTimingKey{}).(*serverTimings)
+	if !ok {
+		return
+	}
+	if !httpguts.ValidHeaderFieldName(name) {
+		log.Printf("rum: ignoring Server-Timing metric %q: name is not an HTTP token", name)
+		return
+	}
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+	if timings.sent {
+		return
+	}
+	timings.entries = append(timings.entries, serverTimingEntry{name: name, dur: dur})
+}
+
+// StartServerTiming starts timing name and returns a function that records
+// the elapsed time with AddServerTiming:
+//
+//	defer StartServerTiming(r.Context(), "db")()
+func StartServerTiming(ctx context.Context, name string) func() {
+	start := time.Now()
+	return func() {
+		AddServerTiming(ctx, name, time.Since(start))
+	}
+}
+
+// timingWriter sets the Server-Timing header right before the response starts.
+type timingWriter struct {
+	http.ResponseWriter
+	start   time.Time
+	timings *serverTimings
+}
+
+func (t *timingWriter) WriteHeader(code int) {
+	t.setHeader()
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *timingWriter) Write(b []byte) (int, error) {
+	t.setHeader()
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *timingWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+func (t *timingWriter) setHeader() {
+	t.timings.mu.Lock()
+	if t.timings.sent {
+		t.timings.mu.Unlock()
+		return
+	}
+	t.timings.sent = true
+	metrics := []string{formatServerTiming("total", time.Since(t.start))}
+	for _, e := range t.timings.entries {
+		metrics = append(metrics, formatServerTiming(e.name, e.dur))
+	}
+	t.timings.mu.Unlock()
+
+	t.Header().Set("Server-Timing", strings.Join(metrics, ", "))
+}
+
+// formatServerTiming formats one metric with its duration in milliseconds.
+func formatServerTiming(name string, dur time.Duration) string {
+	return fmt.Sprintf("%s;dur=%.3f", name, float64(dur)/float64(time.Millisecond))
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statusRecorder stands in for an access-log middleware wrapping
+// ServerTiming: it records the status code the handler sent.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func TestServerTiming(t *testing.T) {
+	var recorder *statusRecorder
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddServerTiming(r.Context(), "db", 1500*time.Microsecond)
+		stop := StartServerTiming(r.Context(), "render")
+		stop()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	})
+	logging := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder = &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	Chain(logging, ServerTiming)(inner).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	header := rec.Header().Get("Server-Timing")
+	want := regexp.MustCompile(`^total;dur=\d+\.\d{3}, db;dur=1\.500, render;dur=\d+\.\d{3}$`)
+	if !want.MatchString(header) {
+		t.Errorf("Server-Timing = %q", header)
+	}
+	if rec.Code != http.StatusCreated || recorder.status != http.StatusCreated {
+		t.Errorf("status = %d, recorded %d; want 201", rec.Code, recorder.status)
+	}
+	if rec.Body.String() != "done" {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
+
+func TestServerTimingWithoutWrite(t *testing.T) {
+	handler := ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !regexp.MustCompile(`^total;dur=\d+\.\d{3}$`).MatchString(rec.Header().Get("Server-Timing")) {
+		t.Errorf("Server-Timing = %q", rec.Header().Get("Server-Timing"))
+	}
+
+	// Outside the middleware, sub-timings are ignored.
+	AddServerTiming(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "db", time.Second)
+}
+
+func TestServerTimingAfterWrite(t *testing.T) {
+	var timings *serverTimings
+	handler := ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timings = r.Context().Value(serverTimingKey{}).(*serverTimings)
+		AddServerTiming(r.Context(), "db", time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		AddServerTiming(r.Context(), "late", time.Millisecond)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if header := rec.Header().Get("Server-Timing"); !strings.Contains(header, "db;dur=") || strings.Contains(header, "late") {
+		t.Errorf("Server-Timing = %q", header)
+	}
+	if len(timings.entries) != 1 {
+		t.Errorf("entries = %d, want 1 (late timing must be dropped)", len(timings.entries))
+	}
+}
+
+func TestServerTimingInvalidName(t *testing.T) {
+	handler := ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddServerTiming(r.Context(), "db, evil;dur=0", time.Millisecond)
+		AddServerTiming(r.Context(), "", time.Millisecond)
+		AddServerTiming(r.Context(), "cache.hit", time.Millisecond)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := regexp.MustCompile(`^total;dur=\d+\.\d{3}, cache\.hit;dur=1\.000$`)
+	if header := rec.Header().Get("Server-Timing"); !want.MatchString(header) {
+		t.Errorf("Server-Timing = %q", header)
+	}
+}
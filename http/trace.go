@@ -0,0 +1,35 @@
+package http
+
+import (
+	"context"
+	"net/http"
+)
+
+// traceIDExtractor returns the trace ID JSONResponseTraced puts in the
+// envelope, or "" when ctx carries none.
+var traceIDExtractor = noTraceID
+
+// SetTraceIDExtractor sets how JSONResponseTraced reads the trace ID from a
+// request context, keeping this package free of a tracing dependency. With
+// OpenTelemetry, pass otel.TraceID from this module's otel package. Passing
+// nil restores the default, which never finds a trace ID. It is meant to be
+// called once during application setup.
+func SetTraceIDExtractor(fn func(ctx context.Context) string) {
+	if fn == nil {
+		fn = noTraceID
+	}
+	traceIDExtractor = fn
+}
+
+func noTraceID(context.Context) string {
+	return ""
+}
+
+// JSONResponseTraced is like JSONResponse but adds the trace ID found in ctx
+// to the envelope as trace_id, so clients can quote it when reporting a
+// failed request. The field is omitted when ctx carries no trace.
+func JSONResponseTraced(ctx context.Context, w http.ResponseWriter, message string, data any, statusCodes ...int) {
+	response := newResponse(message, data, statusCodes...)
+	response.TraceID = traceIDExtractor(ctx)
+	writeResponse(w, response)
+}
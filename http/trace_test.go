@@ -0,0 +1,46 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/4Sigma/rum/otel"
+)
+
+func TestJSONResponseTraced(t *testing.T) {
+	SetTraceIDExtractor(otel.TraceID)
+	defer SetTraceIDExtractor(nil)
+
+	traceID, _ := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	rec := httptest.NewRecorder()
+	JSONResponseTraced(ctx, rec, "not found", nil, http.StatusNotFound)
+
+	var resp Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace_id = %q, want the span's trace ID", resp.TraceID)
+	}
+	if rec.Code != http.StatusNotFound || resp.Code != http.StatusNotFound {
+		t.Errorf("status = %d, code = %d, want %d", rec.Code, resp.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	JSONResponseTraced(context.Background(), rec, "ok", nil)
+	if strings.Contains(rec.Body.String(), "trace_id") {
+		t.Errorf("expected no trace_id without a span, got %s", rec.Body.String())
+	}
+}
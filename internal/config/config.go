@@ -2,7 +2,12 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,12 +17,52 @@ const DefaultConfigFile = "rum.yaml"
 var (
 	ErrConfigNotFound = errors.New("rum.yaml not found")
 	ErrConfigParse    = errors.New("failed to parse rum.yaml")
+	ErrModuleNotFound = errors.New("go.mod not found")
+)
+
+// Defaults used by the generator for unset TemplatesConfig options.
+const (
+	DefaultRoot          = "."
+	DefaultNumericPrefix = "N"
+)
+
+// DefaultStripPrefixes is used when TemplatesConfig.StripPrefixes is nil.
+var DefaultStripPrefixes = []string{"templates/", "template/"}
+
+// Values for TemplatesConfig.RelativeTo.
+const (
+	RelativeToConfig = "config"
+	RelativeToModule = "module"
+)
+
+// Values for TemplatesConfig.NameStyle.
+const (
+	NameStylePath = "path"
+	NameStyleBase = "base"
+	NameStyleFlat = "flat"
+)
+
+// Values for TemplatesConfig.DuplicateDefines.
+const (
+	DuplicateDefinesWarn   = "warn"
+	DuplicateDefinesError  = "error"
+	DuplicateDefinesIgnore = "ignore"
 )
 
 // Config is the root configuration structure for rum.yaml.
 // It's designed to be extensible for future components.
 type Config struct {
 	Templates *TemplatesConfig `yaml:"templates,omitempty"`
+	// Include lists further config files, relative to this one, whose
+	// templates sections are generated as well, e.g. one per team
+	Include []string `yaml:"include,omitempty"`
+	// ExpandEnv replaces ${VAR} references in this file with environment
+	// variables before parsing; an unset variable is an error
+	ExpandEnv bool `yaml:"expand_env,omitempty"`
+
+	// Included holds the templates sections read from Include. Their roots
+	// are relative to the including config, like Templates.Root.
+	Included []*TemplatesConfig `yaml:"-"`
 }
 
 // TemplatesConfig holds configuration for template generation.
@@ -26,16 +71,140 @@ type TemplatesConfig struct {
 	// Templates dirs are relative to this root
 	Root    string `yaml:"root"`
 	Package string `yaml:"package"`
+	// RelativeTo selects what a relative Root is resolved against: "config"
+	// (default) or "module", the directory of the nearest go.mod above the config file
+	RelativeTo string `yaml:"relative_to,omitempty"`
 	// Dirs contains glob patterns for template directories (e.g., "templates/**/*.tmpl")
 	Dirs []string `yaml:"dirs"`
+	// PackagePerDir generates each immediate subdirectory of Root as its own
+	// package named after the directory, with Dirs resolved inside each one;
+	// Package is ignored
+	PackagePerDir bool `yaml:"package_per_dir,omitempty"`
+	// AllowMissingDirs skips Dirs entries whose directory does not exist
+	// instead of failing generation
+	AllowMissingDirs bool `yaml:"allow_missing_dirs,omitempty"`
+	// StripPrefixes lists leading path segments dropped when deriving constant
+	// names (default ["templates/", "template/"])
+	StripPrefixes []string `yaml:"strip_prefixes,omitempty"`
+	// SkipInvalid leaves templates that fail validation out of the generated
+	// code with a warning, failing only if no valid template remains
+	SkipInvalid bool `yaml:"skip_invalid,omitempty"`
+	// NumericPrefix is prepended to constant names starting with a digit (default "N")
+	NumericPrefix string `yaml:"numeric_prefix,omitempty"`
+	// ErrorEmpty fails generation on zero-byte template files instead of warning
+	ErrorEmpty bool `yaml:"error_empty,omitempty"`
+	// Builtins registers the rumtpl built-in helper functions (upper, join, ...)
+	Builtins bool `yaml:"builtins,omitempty"`
+	// Lazy parses templates on first use via sync.Once instead of in init()
+	Lazy bool `yaml:"lazy,omitempty"`
+	// Constructor emits NewManager() instead of a package-level Manager set in init()
+	Constructor bool `yaml:"constructor,omitempty"`
+	// DataTypes maps template path globs (matched against paths relative to
+	// Root, e.g. "templates/emails/*.tmpl") to the Go type of their data; a
+	// typed Render<Const>(rumtpl.Renderer, T) function is emitted for each match
+	DataTypes map[string]string `yaml:"data_types,omitempty"`
+	// DataImports lists import paths needed by the DataTypes types
+	DataImports []string `yaml:"data_imports,omitempty"`
+	// Assets contains glob patterns for non-template files (CSS, JS, images)
+	// embedded into a separate assetsFS exposed by an Assets() accessor
+	Assets []string `yaml:"assets,omitempty"`
+	// ExposeFS emits a Templates() accessor returning the embedded files as an fs.FS
+	ExposeFS bool `yaml:"expose_fs,omitempty"`
+	// EmitManifest also writes templates_manifest.json listing every generated template
+	EmitManifest bool `yaml:"emit_manifest,omitempty"`
+	// SplitByDir writes the constants of each top-level template directory,
+	// after strip_prefixes, to its own templates_<dir>_gen.go file instead
+	// of templates_gen.go
+	SplitByDir bool `yaml:"split_by_dir,omitempty"`
+	// LineEndings normalizes text files written by rum render: "lf", "crlf",
+	// or empty to keep the output as rendered
+	LineEndings string `yaml:"line_endings,omitempty"`
+	// Manifest is a file (relative to Root) listing template paths one per
+	// line; when set, exactly those templates are used, in that order, instead of Dirs
+	Manifest string `yaml:"manifest,omitempty"`
+	// NameStyle selects the runtime template names and the constant names
+	// derived from them: "path" (relative path, the default), "base" (file
+	// name only) or "flat" (path without strip_prefixes, "/" replaced by "_")
+	NameStyle string `yaml:"name_style,omitempty"`
+	// DuplicateDefines sets what happens when several template files
+	// {{define}} the same name: "warn" (the default), "error" or "ignore"
+	DuplicateDefines string `yaml:"duplicate_defines,omitempty"`
+	// KeepBOM keeps a leading UTF-8 byte order mark in template files
+	// instead of stripping it when they are validated, rendered or loaded
+	KeepBOM bool `yaml:"keep_bom,omitempty"`
 }
 
-// Load reads and parses the rum.yaml configuration file.
+// legacyTemplatesConfig holds the keys of the older config schema that are
+// still accepted for backward compatibility.
+type legacyTemplatesConfig struct {
+	OutputFile    string   `yaml:"output_file"`
+	OutputPackage string   `yaml:"output_package"`
+	Sources       []string `yaml:"sources"`
+}
+
+// UnmarshalYAML decodes the templates section, mapping the deprecated
+// output_file, output_package and sources keys onto Root, Package and Dirs
+// with a warning. When both spellings are present the current key wins.
+func (t *TemplatesConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain TemplatesConfig
+	if err := value.Decode((*plain)(t)); err != nil {
+		return err
+	}
+
+	var legacy legacyTemplatesConfig
+	if err := value.Decode(&legacy); err != nil {
+		return err
+	}
+
+	if legacy.OutputFile != "" {
+		deprecated("output_file", "root")
+		if t.Root == "" {
+			t.Root = filepath.Dir(legacy.OutputFile)
+		}
+	}
+	if legacy.OutputPackage != "" {
+		deprecated("output_package", "package")
+		if t.Package == "" {
+			t.Package = legacy.OutputPackage
+		}
+	}
+	if legacy.Sources != nil {
+		deprecated("sources", "dirs")
+		if t.Dirs == nil {
+			t.Dirs = legacy.Sources
+		}
+	}
+	return nil
+}
+
+// deprecated warns that a config key has been renamed.
+func deprecated(oldKey, newKey string) {
+	fmt.Fprintf(os.Stderr, "warning: templates.%s is deprecated, use templates.%s\n", oldKey, newKey)
+}
+
+// Load reads and parses the rum.yaml configuration file. In a file that sets
+// expand_env: true, references to environment variables written as ${VAR}
+// are expanded before parsing, and an unset variable is an error. Files
+// listed under include are loaded into Included. A relative templates root
+// is made absolute according to relative_to.
 func Load(path string) (*Config, error) {
 	if path == "" {
 		path = DefaultConfigFile
 	}
 
+	cfg, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.loadIncludes(path); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readConfig parses a single config file without following includes.
+func readConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -44,15 +213,214 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	var opts struct {
+		ExpandEnv bool `yaml:"expand_env"`
+	}
+	if err := yaml.Unmarshal(data, &opts); err != nil {
+		return nil, errors.Join(ErrConfigParse, err)
+	}
+	if opts.ExpandEnv {
+		if data, err = expandEnv(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, errors.Join(ErrConfigParse, err)
 	}
 
+	if cfg.Templates != nil {
+		if err := cfg.Templates.resolveRelativeTo(path); err != nil {
+			return nil, err
+		}
+	}
+
 	return &cfg, nil
 }
 
+// loadIncludes reads the templates section of every included file, whose
+// paths are relative to the directory of the config at path, and rejects
+// template sets that would write the same templates_gen.go. Relative roots
+// of included files are resolved against their own directory by readConfig.
+// Outputs that depend on the discovered templates, such as split_by_dir
+// group files, are compared by generator.CheckOutputs.
+func (c *Config) loadIncludes(path string) error {
+	for _, include := range c.Include {
+		includePath := include
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(path), include)
+		}
+
+		included, err := readConfig(includePath)
+		if err != nil {
+			return fmt.Errorf("include %s: %w", include, err)
+		}
+		if len(included.Include) > 0 {
+			return fmt.Errorf("include %s: %w: nested includes are not supported", include, ErrConfigParse)
+		}
+		if included.Templates == nil {
+			continue
+		}
+
+		c.Included = append(c.Included, included.Templates)
+	}
+
+	outputs := make(map[string]bool)
+	for _, t := range c.TemplateSets() {
+		root := t.Root
+		if root == "" {
+			root = DefaultRoot
+		}
+		output := filepath.Join(root, "templates_gen.go")
+		if outputs[output] {
+			return fmt.Errorf("%w: more than one templates section generates %s", ErrConfigParse, output)
+		}
+		outputs[output] = true
+	}
+	return nil
+}
+
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} references with the value of the environment
+// variable, failing if any is unset. Bare $VAR is left alone so values like
+// regexps keep their dollars.
+func expandEnv(data []byte) ([]byte, error) {
+	var missing []string
+	expanded := envRefPattern.ReplaceAllFunc(data, func(ref []byte) []byte {
+		name := string(ref[2 : len(ref)-1])
+		value, ok := os.LookupEnv(name)
+		if !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+		return []byte(value)
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: environment variables not set: %s", ErrConfigParse, strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// WithDefaults returns a copy of t with unset options filled in with the
+// defaults the generator applies, i.e. the configuration it acts on.
+func (t *TemplatesConfig) WithDefaults() *TemplatesConfig {
+	c := *t
+	if c.Root == "" {
+		c.Root = DefaultRoot
+	}
+	if c.RelativeTo == "" {
+		c.RelativeTo = RelativeToConfig
+	}
+	if c.NumericPrefix == "" {
+		c.NumericPrefix = DefaultNumericPrefix
+	}
+	if c.StripPrefixes == nil {
+		c.StripPrefixes = DefaultStripPrefixes
+	}
+	if c.NameStyle == "" {
+		c.NameStyle = NameStylePath
+	}
+	if c.DuplicateDefines == "" {
+		c.DuplicateDefines = DuplicateDefinesWarn
+	}
+	return &c
+}
+
+// resolveRelativeTo makes a relative Root absolute, resolving it against the
+// directory of the config file at configPath, or for "module" against the
+// nearest go.mod above it. Load and LoadDefault thus behave the same
+// wherever rum is run from.
+func (t *TemplatesConfig) resolveRelativeTo(configPath string) error {
+	switch t.RelativeTo {
+	case "", RelativeToConfig, RelativeToModule:
+	default:
+		return fmt.Errorf("%w: relative_to must be %q or %q, got %q", ErrConfigParse, RelativeToConfig, RelativeToModule, t.RelativeTo)
+	}
+
+	if filepath.IsAbs(t.Root) {
+		return nil
+	}
+
+	base, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return err
+	}
+	if t.RelativeTo == RelativeToModule {
+		if base, err = FindModuleRoot(base); err != nil {
+			return err
+		}
+	}
+	t.Root = filepath.Join(base, t.Root)
+	return nil
+}
+
+// FindModuleRoot walks up from dir and returns the first directory
+// containing a go.mod file, or ErrModuleNotFound.
+func FindModuleRoot(dir string) (string, error) {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !info.IsDir() {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrModuleNotFound
+		}
+		dir = parent
+	}
+}
+
+// LoadDefault finds the nearest rum.yaml in the current directory or one of
+// its parents, like git does for .git, and loads it with Load.
+func LoadDefault() (*Config, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := Find(wd)
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(path)
+}
+
+// Find walks up from dir to the filesystem root and returns the path of the
+// first DefaultConfigFile found, or ErrConfigNotFound.
+func Find(dir string) (string, error) {
+	for {
+		path := filepath.Join(dir, DefaultConfigFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrConfigNotFound
+		}
+		dir = parent
+	}
+}
+
 // HasTemplates returns true if templates configuration is present.
 func (c *Config) HasTemplates() bool {
-	return c.Templates != nil && len(c.Templates.Dirs) > 0
+	return c.Templates.configured()
+}
+
+// TemplateSets returns every configured templates section: the config's own
+// followed by the included ones, in include order.
+func (c *Config) TemplateSets() []*TemplatesConfig {
+	var sets []*TemplatesConfig
+	for _, t := range append([]*TemplatesConfig{c.Templates}, c.Included...) {
+		if t.configured() {
+			sets = append(sets, t)
+		}
+	}
+	return sets
+}
+
+func (t *TemplatesConfig) configured() bool {
+	return t != nil && (len(t.Dirs) > 0 || t.Manifest != "")
 }
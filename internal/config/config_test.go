@@ -1,8 +1,11 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -46,8 +49,8 @@ templates:
 		if cfg.Templates == nil {
 			t.Fatal("expected templates config")
 		}
-		if cfg.Templates.Root != "." {
-			t.Errorf("expected root '.', got %q", cfg.Templates.Root)
+		if cfg.Templates.Root != dir {
+			t.Errorf("expected root %q, got %q", dir, cfg.Templates.Root)
 		}
 		if cfg.Templates.Package != "main" {
 			t.Errorf("expected package 'main', got %q", cfg.Templates.Package)
@@ -89,6 +92,11 @@ func TestHasTemplates(t *testing.T) {
 			config: Config{Templates: &TemplatesConfig{Dirs: []string{}}},
 			want:   false,
 		},
+		{
+			name:   "with manifest",
+			config: Config{Templates: &TemplatesConfig{Manifest: "templates.list"}},
+			want:   true,
+		},
 		{
 			name: "with dirs",
 			config: Config{Templates: &TemplatesConfig{
@@ -106,3 +114,238 @@ func TestHasTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadDefault(t *testing.T) {
+	t.Run("found in parent", func(t *testing.T) {
+		dir := t.TempDir()
+		content := `
+templates:
+  root: "web"
+  package: "web"
+  dirs:
+    - "templates/*.tmpl"
+`
+		os.WriteFile(filepath.Join(dir, "rum.yaml"), []byte(content), 0644)
+
+		nested := filepath.Join(dir, "internal", "handlers")
+		os.MkdirAll(nested, 0755)
+		t.Chdir(nested)
+
+		cfg, err := LoadDefault()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Templates == nil || cfg.Templates.Package != "web" {
+			t.Fatalf("expected parent config to be loaded, got %+v", cfg.Templates)
+		}
+
+		wantRoot := filepath.Join(dir, "web")
+		if cfg.Templates.Root != wantRoot {
+			t.Errorf("expected root %q, got %q", wantRoot, cfg.Templates.Root)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := Find(t.TempDir())
+		if err != ErrConfigNotFound {
+			t.Errorf("expected ErrConfigNotFound, got %v", err)
+		}
+	})
+}
+
+func TestLoadRelativeToModule(t *testing.T) {
+	module := t.TempDir()
+	os.WriteFile(filepath.Join(module, "go.mod"), []byte("module example.com/app\n"), 0644)
+
+	pkg := filepath.Join(module, "internal", "views")
+	os.MkdirAll(filepath.Join(pkg, "templates"), 0755)
+	os.WriteFile(filepath.Join(pkg, "templates", "home.html.tmpl"), []byte("home"), 0644)
+
+	content := `
+templates:
+  relative_to: module
+  root: "internal/views"
+  package: "views"
+  dirs:
+    - "templates/*.tmpl"
+`
+	os.WriteFile(filepath.Join(pkg, "rum.yaml"), []byte(content), 0644)
+	t.Chdir(pkg)
+
+	cfg, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Templates.Root != pkg {
+		t.Errorf("expected root %q, got %q", pkg, cfg.Templates.Root)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(cfg.Templates.Root, cfg.Templates.Dirs[0]))
+	if len(matches) != 1 {
+		t.Errorf("module-relative glob matched %v", matches)
+	}
+}
+
+func TestLoadRelativeToConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rum.yaml")
+	os.WriteFile(path, []byte("templates:\n  root: \"web\"\n  dirs: [\"templates/*.tmpl\"]\n"), 0644)
+	t.Chdir(t.TempDir())
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(dir, "web"); cfg.Templates.Root != want {
+		t.Errorf("expected root %q, got %q", want, cfg.Templates.Root)
+	}
+}
+
+func TestLoadRelativeToInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rum.yaml")
+	os.WriteFile(path, []byte("templates:\n  relative_to: workspace\n"), 0644)
+
+	if _, err := Load(path); !errors.Is(err, ErrConfigParse) {
+		t.Errorf("expected ErrConfigParse, got %v", err)
+	}
+}
+
+func TestLoadLegacyKeys(t *testing.T) {
+	variants := map[string]string{
+		"current": `
+templates:
+  root: "internal/views"
+  package: "views"
+  dirs:
+    - "templates/*.tmpl"
+`,
+		"legacy": `
+templates:
+  output_file: "internal/views/templates_gen.go"
+  output_package: "views"
+  sources:
+    - "templates/*.tmpl"
+`,
+	}
+
+	loaded := make(map[string]*TemplatesConfig)
+	for name, content := range variants {
+		path := filepath.Join(t.TempDir(), "rum.yaml")
+		os.WriteFile(path, []byte(content), 0644)
+
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		// Roots are resolved against each file's own temp directory.
+		cfg.Templates.Root, _ = filepath.Rel(filepath.Dir(path), cfg.Templates.Root)
+		loaded[name] = cfg.Templates
+	}
+
+	if !reflect.DeepEqual(loaded["current"], loaded["legacy"]) {
+		t.Errorf("legacy config = %+v, want %+v", loaded["legacy"], loaded["current"])
+	}
+
+	t.Run("current key wins", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rum.yaml")
+		os.WriteFile(path, []byte("templates:\n  package: new\n  output_package: old\n"), 0644)
+
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Templates.Package != "new" {
+			t.Errorf("package = %q, want %q", cfg.Templates.Package, "new")
+		}
+	})
+}
+
+func TestLoadExpandsEnv(t *testing.T) {
+	t.Setenv("RUM_TEST_ROOT", "web")
+	t.Setenv("RUM_TEST_EMPTY", "")
+
+	tests := []struct {
+		name     string
+		content  string
+		wantRoot string
+		wantErr  string
+	}{
+		{
+			name:     "expand_env",
+			content:  "expand_env: true\ntemplates:\n  root: \"${RUM_TEST_ROOT}/views${RUM_TEST_EMPTY}\"\n  package: \"$notexpanded\"\n",
+			wantRoot: "web/views",
+		},
+		{
+			name:     "off by default",
+			content:  "templates:\n  root: \"${RUM_TEST_ROOT}/views\"\n  package: \"$notexpanded\"\n",
+			wantRoot: "${RUM_TEST_ROOT}/views",
+		},
+		{
+			name:    "unset variable",
+			content: "expand_env: true\ntemplates:\n  root: \"${RUM_TEST_UNSET}/${RUM_TEST_ROOT}\"\n",
+			wantErr: "environment variables not set: RUM_TEST_UNSET",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rum.yaml")
+			os.WriteFile(path, []byte(tt.content), 0644)
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrConfigParse) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := filepath.Join(filepath.Dir(path), tt.wantRoot); cfg.Templates.Root != want {
+				t.Errorf("root = %q, want %q", cfg.Templates.Root, want)
+			}
+			if cfg.Templates.Package != "$notexpanded" {
+				t.Errorf("package = %q, want bare $ references left alone", cfg.Templates.Package)
+			}
+		})
+	}
+}
+
+func TestLoadInclude(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "team-a"), 0755)
+	os.MkdirAll(filepath.Join(dir, "team-b"), 0755)
+
+	os.WriteFile(filepath.Join(dir, "rum.yaml"), []byte("include:\n  - team-a/rum.yaml\n  - team-b/rum.yaml\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "team-a", "rum.yaml"), []byte("templates:\n  root: views\n  package: views\n  dirs: [\"*.tmpl\"]\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "team-b", "rum.yaml"), []byte("templates:\n  package: emails\n  dirs: [\"*.tmpl\"]\n"), 0644)
+
+	cfg, err := Load(filepath.Join(dir, "rum.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sets := cfg.TemplateSets()
+	if len(sets) != 2 {
+		t.Fatalf("expected 2 template sets, got %d", len(sets))
+	}
+	if sets[0].Root != filepath.Join(dir, "team-a", "views") || sets[1].Root != filepath.Join(dir, "team-b") {
+		t.Errorf("roots = %q, %q", sets[0].Root, sets[1].Root)
+	}
+
+	t.Run("conflicting outputs", func(t *testing.T) {
+		os.WriteFile(filepath.Join(dir, "conflict.yaml"), []byte(`
+templates:
+  root: team-b
+  package: other
+  dirs: ["*.tmpl"]
+include:
+  - team-b/rum.yaml
+`), 0644)
+
+		if _, err := Load(filepath.Join(dir, "conflict.yaml")); !errors.Is(err, ErrConfigParse) {
+			t.Errorf("expected ErrConfigParse for conflicting outputs, got %v", err)
+		}
+	})
+}
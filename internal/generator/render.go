@@ -0,0 +1,358 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/4Sigma/rum/internal/config"
+	rumtpl "github.com/4Sigma/rum/template_manager"
+)
+
+// StaticRenderer renders every discovered template to a file under an
+// output directory, for static sites and scaffolding.
+//
+// Data for each template is resolved in order of increasing precedence:
+//  1. the global data map;
+//  2. the value under the template's constant name (e.g. "PagesHome"), which
+//     replaces the global data when present;
+//  3. a sibling data file (home.html.tmpl -> home.html.data.yaml, .yml or
+//     .json), whose top-level keys are merged over the result.
+type StaticRenderer struct {
+	gen    *TemplatesGenerator
+	outDir string
+}
+
+// NewStaticRenderer creates a renderer writing into outDir.
+func NewStaticRenderer(cfg *config.TemplatesConfig, outDir string) *StaticRenderer {
+	return &StaticRenderer{gen: NewTemplatesGenerator(cfg), outDir: outDir}
+}
+
+// Render renders all templates with data. Output paths mirror the template
+// paths relative to the root, without the .tmpl extension.
+func (r *StaticRenderer) Render(data map[string]any) error {
+	templates, err := r.gen.discover()
+	if err != nil {
+		return err
+	}
+
+	m, err := r.parse(templates)
+	if err != nil {
+		return err
+	}
+	return r.render(m, templates, data)
+}
+
+// render writes the output of templates, rendered by m, into the output
+// directory.
+func (r *StaticRenderer) render(m *rumtpl.Manager, templates []TemplateInfo, data map[string]any) error {
+	eol, err := lineEnding(r.gen.config.LineEndings)
+	if err != nil {
+		return err
+	}
+
+	root := r.gen.config.Root
+	if root == "" {
+		root = "."
+	}
+
+	for _, t := range templates {
+		pageData, err := pageDataFor(filepath.Join(root, t.RelPath), selectData(data, t.ConstName))
+		if err != nil {
+			return err
+		}
+
+		output, err := m.Render(rumtpl.Name(t.Name), pageData)
+		if err != nil {
+			return fmt.Errorf("rendering %s: %w", t.RelPath, err)
+		}
+
+		outputFile := filepath.Join(r.outDir, strings.TrimSuffix(t.RelPath, ".tmpl"))
+		if eol != "" && textExts[strings.ToLower(filepath.Ext(outputFile))] {
+			output = normalizeLineEndings(output, eol)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+			return fmt.Errorf("creating output directory: %w", err)
+		}
+		if err := os.WriteFile(outputFile, output, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", outputFile, err)
+		}
+	}
+
+	fmt.Printf("Rendered %d templates into %s\n", len(templates), r.outDir)
+	return nil
+}
+
+// Watch renders all templates and then polls them every interval
+// (DefaultWatchInterval if zero) until ctx is cancelled. When templates
+// change, only they and the templates including them, directly or through
+// other partials, are rendered again; a template being added or removed
+// renders everything. Errors are reported on stderr and do not stop the loop.
+func (r *StaticRenderer) Watch(ctx context.Context, data map[string]any, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &Watcher{gen: r.gen, interval: interval}
+
+	last := w.snapshot()
+	if err := r.Render(data); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current := w.snapshot()
+			if maps.Equal(current, last) {
+				continue
+			}
+
+			var err error
+			if changed, ok := changedFiles(last, current); ok {
+				err = r.RenderChanged(data, changed)
+			} else {
+				err = r.Render(data)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			}
+			last = current
+		}
+	}
+}
+
+// changedFiles returns the files whose state differs between two snapshots,
+// or false if a file was added or removed.
+func changedFiles(before, after map[string]fileState) ([]string, bool) {
+	if len(before) != len(after) {
+		return nil, false
+	}
+	var changed []string
+	for path, state := range after {
+		prev, ok := before[path]
+		if !ok {
+			return nil, false
+		}
+		if prev != state {
+			changed = append(changed, path)
+		}
+	}
+	return changed, true
+}
+
+// RenderChanged renders the templates at the changed relative paths again,
+// together with every template that includes one of them, as reported by
+// Manager.Dependents. Editing a partial thus refreshes the pages using it.
+func (r *StaticRenderer) RenderChanged(data map[string]any, changed []string) error {
+	templates, err := r.gen.discover()
+	if err != nil {
+		return err
+	}
+
+	m, err := r.parse(templates)
+	if err != nil {
+		return err
+	}
+
+	affected := make(map[rumtpl.Name]bool)
+	for _, t := range templates {
+		if !slices.Contains(changed, t.RelPath) {
+			continue
+		}
+		name := rumtpl.Name(t.Name)
+		affected[name] = true
+		for _, dependent := range m.Dependents(name) {
+			affected[dependent] = true
+		}
+	}
+
+	var selected []TemplateInfo
+	for _, t := range templates {
+		if affected[rumtpl.Name(t.Name)] {
+			selected = append(selected, t)
+		}
+	}
+	return r.render(m, selected, data)
+}
+
+// parse loads all templates into one manager so they can include each other.
+func (r *StaticRenderer) parse(templates []TemplateInfo) (*rumtpl.Manager, error) {
+	root := r.gen.config.Root
+	if root == "" {
+		root = "."
+	}
+
+	names := make(map[string]rumtpl.Name, len(templates))
+	for _, t := range templates {
+		names[t.RelPath] = rumtpl.Name(t.Name)
+	}
+
+	var funcs template.FuncMap
+	if r.gen.config.Builtins {
+		funcs = rumtpl.Builtins()
+	}
+	var opts []rumtpl.ManagerOption
+	if r.gen.config.KeepBOM {
+		opts = append(opts, rumtpl.KeepBOM())
+	}
+
+	m, err := rumtpl.NewManagerFromFSNamed(newListedFS(os.DirFS(root), templates), "*", names, funcs, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing templates: %w", err)
+	}
+	return m, nil
+}
+
+// listedFS exposes only the discovered templates of a file system, and the
+// directories leading to them, so a manager parses exactly those files.
+type listedFS struct {
+	fs.FS
+	files map[string]bool // template paths and their parent directories
+}
+
+func newListedFS(fsys fs.FS, templates []TemplateInfo) listedFS {
+	files := make(map[string]bool)
+	for _, t := range templates {
+		for p := t.RelPath; p != "."; p = path.Dir(p) {
+			files[p] = true
+		}
+	}
+	return listedFS{FS: fsys, files: files}
+}
+
+// ReadDir implements fs.ReadDirFS, leaving out unlisted entries.
+func (l listedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(l.FS, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var listed []fs.DirEntry
+	for _, entry := range entries {
+		if l.files[path.Join(name, entry.Name())] {
+			listed = append(listed, entry)
+		}
+	}
+	return listed, nil
+}
+
+// textExts lists the output extensions whose line endings may be normalized;
+// anything else is written byte for byte.
+var textExts = map[string]bool{
+	".html": true, ".htm": true, ".xml": true, ".svg": true, ".txt": true,
+	".md": true, ".css": true, ".js": true, ".json": true, ".yaml": true,
+	".yml": true, ".toml": true, ".ini": true, ".csv": true, ".sql": true,
+	".go": true, ".sh": true, ".bat": true, ".cmd": true, ".ps1": true,
+}
+
+// lineEnding maps the line_endings option to the line terminator to use.
+func lineEnding(option string) (string, error) {
+	switch strings.ToLower(option) {
+	case "":
+		return "", nil
+	case "lf":
+		return "\n", nil
+	case "crlf":
+		return "\r\n", nil
+	default:
+		return "", fmt.Errorf("invalid line_endings %q: expected lf or crlf", option)
+	}
+}
+
+// normalizeLineEndings rewrites every line break in data to eol.
+func normalizeLineEndings(data []byte, eol string) []byte {
+	lf := bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if eol == "\n" {
+		return lf
+	}
+	return bytes.ReplaceAll(lf, []byte("\n"), []byte(eol))
+}
+
+// selectData applies the per-template data selection convention.
+func selectData(data map[string]any, constName string) any {
+	if v, ok := data[constName]; ok {
+		return v
+	}
+	return data
+}
+
+// pageDataExts lists the sibling data file extensions, in lookup order.
+var pageDataExts = []string{".data.yaml", ".data.yml", ".data.json"}
+
+// pageDataFor merges the sibling data file of templatePath, if any, over base.
+// A data file can only be merged into map data (or none), so any other base
+// is an error rather than being dropped.
+func pageDataFor(templatePath string, base any) (any, error) {
+	stem := strings.TrimSuffix(templatePath, ".tmpl")
+	for _, ext := range pageDataExts {
+		path := stem + ext
+		if _, err := os.Stat(path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		page, err := LoadData(path)
+		if err != nil {
+			return nil, err
+		}
+
+		merged := make(map[string]any)
+		if base != nil {
+			m, ok := base.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot merge %s into data of type %T: expected an object", path, base)
+			}
+			for k, v := range m {
+				merged[k] = v
+			}
+		}
+		for k, v := range page {
+			merged[k] = v
+		}
+		return merged, nil
+	}
+	return base, nil
+}
+
+// LoadData reads a JSON or YAML data file, chosen by its extension.
+func LoadData(path string) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, &data)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &data)
+	default:
+		return nil, fmt.Errorf("unsupported data file %s: expected .json, .yaml or .yml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,280 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Sigma/rum/internal/config"
+)
+
+func TestStaticRender(t *testing.T) {
+	dir := t.TempDir()
+	pagesDir := filepath.Join(dir, "templates", "pages")
+	os.MkdirAll(pagesDir, 0755)
+
+	os.WriteFile(filepath.Join(pagesDir, "home.html.tmpl"), []byte("<h1>{{.Site}}</h1>"), 0644)
+	os.WriteFile(filepath.Join(pagesDir, "about.html.tmpl"), []byte("<p>{{.Title}}</p>"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:    dir,
+		Package: "main",
+		Dirs:    []string{"templates/**/*.tmpl"},
+	}
+
+	data := map[string]any{
+		"Site":       "Rum",
+		"PagesAbout": map[string]any{"Title": "About us"},
+	}
+
+	outDir := filepath.Join(dir, "dist")
+	if err := NewStaticRenderer(cfg, outDir).Render(data); err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"templates/pages/home.html", "<h1>Rum</h1>"},
+		{"templates/pages/about.html", "<p>About us</p>"},
+	}
+
+	for _, tt := range tests {
+		content, err := os.ReadFile(filepath.Join(outDir, tt.file))
+		if err != nil {
+			t.Fatalf("reading %s: %v", tt.file, err)
+		}
+		if string(content) != tt.want {
+			t.Errorf("%s = %q, want %q", tt.file, content, tt.want)
+		}
+	}
+}
+
+func TestLoadData(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "data.json")
+	os.WriteFile(jsonPath, []byte(`{"Site": "Rum"}`), 0644)
+	yamlPath := filepath.Join(dir, "data.yaml")
+	os.WriteFile(yamlPath, []byte("Site: Rum\n"), 0644)
+
+	for _, path := range []string{jsonPath, yamlPath} {
+		data, err := LoadData(path)
+		if err != nil {
+			t.Fatalf("LoadData(%s) error: %v", path, err)
+		}
+		if data["Site"] != "Rum" {
+			t.Errorf("LoadData(%s) Site = %v, want %q", path, data["Site"], "Rum")
+		}
+	}
+
+	if _, err := LoadData(filepath.Join(dir, "data.txt")); err == nil {
+		t.Error("expected error for unsupported extension")
+	}
+}
+
+func TestStaticRenderPageData(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir := filepath.Join(dir, "templates")
+	os.MkdirAll(templatesDir, 0755)
+
+	os.WriteFile(filepath.Join(templatesDir, "home.html.tmpl"), []byte("{{.Site}}: {{.Title}}"), 0644)
+	os.WriteFile(filepath.Join(templatesDir, "home.html.data.yaml"), []byte("Title: Welcome\n"), 0644)
+	os.WriteFile(filepath.Join(templatesDir, "about.html.tmpl"), []byte("{{.Site}}: {{.Title}}"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:    dir,
+		Package: "main",
+		Dirs:    []string{"templates/*.tmpl"},
+	}
+
+	data := map[string]any{"Site": "Rum", "Title": "Default"}
+
+	outDir := filepath.Join(dir, "dist")
+	if err := NewStaticRenderer(cfg, outDir).Render(data); err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"templates/home.html", "Rum: Welcome"},
+		{"templates/about.html", "Rum: Default"},
+	}
+
+	for _, tt := range tests {
+		content, err := os.ReadFile(filepath.Join(outDir, tt.file))
+		if err != nil {
+			t.Fatalf("reading %s: %v", tt.file, err)
+		}
+		if string(content) != tt.want {
+			t.Errorf("%s = %q, want %q", tt.file, content, tt.want)
+		}
+	}
+}
+
+func TestPageDataForErrors(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "home.html.tmpl"), []byte("{{.}}"), 0644)
+	os.WriteFile(filepath.Join(dir, "home.html.data.yaml"), []byte("Title: Welcome\n"), 0644)
+
+	tests := []struct {
+		name     string
+		template string
+		base     any
+		wantErr  string
+	}{
+		{"list data", filepath.Join(dir, "home.html.tmpl"), []any{"a"}, "cannot merge"},
+		{"string data", filepath.Join(dir, "home.html.tmpl"), "site", "cannot merge"},
+		// A path through a regular file fails with ENOTDIR, not ErrNotExist.
+		{"stat error", filepath.Join(dir, "home.html.tmpl", "x.tmpl"), nil, "not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pageDataFor(tt.template, tt.base)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("pageDataFor() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Without a data file the base passes through whatever its type.
+	if got, err := pageDataFor(filepath.Join(dir, "about.html.tmpl"), "site"); err != nil || got != "site" {
+		t.Errorf("pageDataFor() = %v, %v; want site, nil", got, err)
+	}
+}
+
+func TestStaticRenderLineEndings(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+
+	os.WriteFile(filepath.Join(dir, "templates", "page.html.tmpl"), []byte("<p>\n{{.}}\r\n</p>\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "blob.bin.tmpl"), []byte("a\nb"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:        dir,
+		Package:     "main",
+		Dirs:        []string{"templates/*.tmpl"},
+		LineEndings: "crlf",
+	}
+
+	outDir := filepath.Join(dir, "dist")
+	if err := NewStaticRenderer(cfg, outDir).Render(map[string]any{}); err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	page, _ := os.ReadFile(filepath.Join(outDir, "templates", "page.html"))
+	if want := "<p>\r\nmap[]\r\n</p>\r\n"; string(page) != want {
+		t.Errorf("page.html = %q, want %q", page, want)
+	}
+
+	blob, _ := os.ReadFile(filepath.Join(outDir, "templates", "blob.bin"))
+	if string(blob) != "a\nb" {
+		t.Errorf("non-text output was modified: %q", blob)
+	}
+
+	cfg.LineEndings = "cr"
+	if err := NewStaticRenderer(cfg, outDir).Render(map[string]any{}); err == nil {
+		t.Error("expected error for invalid line_endings")
+	}
+}
+
+func TestStaticRenderBOM(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "page.html.tmpl"), []byte("\xEF\xBB\xBF<p>{{.Site}}</p>"), 0644)
+
+	tests := []struct {
+		name    string
+		keepBOM bool
+		want    string
+	}{
+		{"stripped by default", false, "<p>Rum</p>"},
+		{"keep_bom", true, "\xEF\xBB\xBF<p>Rum</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.TemplatesConfig{
+				Root:    dir,
+				Package: "main",
+				Dirs:    []string{"templates/*.tmpl"},
+				KeepBOM: tt.keepBOM,
+			}
+
+			outDir := filepath.Join(dir, "dist")
+			if err := NewStaticRenderer(cfg, outDir).Render(map[string]any{"Site": "Rum"}); err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			page, _ := os.ReadFile(filepath.Join(outDir, "templates", "page.html"))
+			if string(page) != tt.want {
+				t.Errorf("page.html = %q, want %q", page, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticRenderChanged(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "nav.tmpl"), []byte(`{{define "nav"}}v1{{end}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "layout.tmpl"), []byte(`{{define "layout"}}[{{template "nav"}}]{{end}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte(`home {{template "layout"}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "about.html.tmpl"), []byte(`about`), 0644)
+
+	cfg := &config.TemplatesConfig{Root: dir, Package: "main", Dirs: []string{"templates/*.tmpl"}}
+	outDir := filepath.Join(dir, "dist")
+	r := NewStaticRenderer(cfg, outDir)
+	if err := r.Render(nil); err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	// Editing nav re-renders home through layout, and leaves about alone.
+	os.WriteFile(filepath.Join(dir, "templates", "nav.tmpl"), []byte(`{{define "nav"}}v2{{end}}`), 0644)
+	os.Remove(filepath.Join(outDir, "templates", "about.html"))
+	if err := r.RenderChanged(nil, []string{"templates/nav.tmpl"}); err != nil {
+		t.Fatalf("RenderChanged() error: %v", err)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(outDir, "templates", "home.html")); string(content) != "home [v2]" {
+		t.Errorf("home.html = %q, want %q", content, "home [v2]")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "templates", "about.html")); !os.IsNotExist(err) {
+		t.Error("about.html does not include nav and should not be rendered again")
+	}
+}
+
+func TestStaticRenderWatch(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "nav.tmpl"), []byte(`{{define "nav"}}v1{{end}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte(`home {{template "nav"}}`), 0644)
+
+	cfg := &config.TemplatesConfig{Root: dir, Package: "main", Dirs: []string{"templates/*.tmpl"}}
+	outDir := filepath.Join(dir, "dist")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewStaticRenderer(cfg, outDir).Watch(ctx, nil, 10*time.Millisecond) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	os.WriteFile(filepath.Join(dir, "templates", "nav.tmpl"), []byte(`{{define "nav"}}v2{{end}}`), 0644)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if content, _ := os.ReadFile(filepath.Join(outDir, "templates", "home.html")); string(content) == "home v2" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("page including the edited partial was not rendered again")
+}
@@ -2,26 +2,50 @@ package generator
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"go/token"
 	"html/template"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template/parse"
+	"unicode"
 
 	"github.com/4Sigma/rum/internal/config"
+	rumtpl "github.com/4Sigma/rum/template_manager"
 )
 
+// ErrNoTemplates is returned when the configured dirs match no template files.
+var ErrNoTemplates = errors.New("no templates found in configured dirs")
+
+// defaultNumericPrefix is prepended to constant names that would start with a digit.
+const defaultNumericPrefix = config.DefaultNumericPrefix
+
 // TemplateInfo holds information about a discovered template.
 type TemplateInfo struct {
 	FileName  string // Original filename: "api.template.yaml.tmpl"
 	RelPath   string // Relative path from root: "templates/openapi/api.template.yaml.tmpl"
 	ConstName string // PascalCase name with path prefix: "OpenapiApiTemplate"
+	Name      string // Runtime template name per name_style; RelPath by default
+	Checksum  string // SHA-256 of the content, set when generating the file
+	DataType  string // Go type of the template data from data_types, if any
 }
 
 // TemplatesGenerator generates Go code for template management.
 type TemplatesGenerator struct {
 	config *config.TemplatesConfig
+
+	// packageDir is set for the generators of package_per_dir, where an
+	// assets pattern may match nothing in some packages.
+	packageDir bool
+	// nameErr is set when packageDir's directory name is no valid package.
+	nameErr error
 }
 
 // NewTemplatesGenerator creates a new template generator.
@@ -31,37 +55,352 @@ func NewTemplatesGenerator(cfg *config.TemplatesConfig) *TemplatesGenerator {
 
 // Generate scans template sources and generates the output file.
 func (g *TemplatesGenerator) Generate() error {
+	if g.config.Lazy && g.config.Constructor {
+		return fmt.Errorf("the lazy and constructor options cannot be combined")
+	}
+	switch g.config.NameStyle {
+	case "", config.NameStylePath, config.NameStyleBase, config.NameStyleFlat:
+	default:
+		return fmt.Errorf("invalid name_style %q: expected path, base or flat", g.config.NameStyle)
+	}
+	switch g.config.DuplicateDefines {
+	case "", config.DuplicateDefinesWarn, config.DuplicateDefinesError, config.DuplicateDefinesIgnore:
+	default:
+		return fmt.Errorf("invalid duplicate_defines %q: expected warn, error or ignore", g.config.DuplicateDefines)
+	}
+	// The prefix is put in front of names starting with a digit, so it has
+	// to make one a valid identifier.
+	if p := g.config.NumericPrefix; p != "" && !token.IsIdentifier(p+"0") {
+		return fmt.Errorf("invalid numeric_prefix %q: must start with a letter or underscore and contain only letters, digits and underscores", p)
+	}
+	if g.config.PackagePerDir {
+		return g.generatePerDir()
+	}
+
+	allTemplates, err := g.discover()
+	if err != nil {
+		return err
+	}
+
+	// Validate templates syntax
+	skipped := false
+	defines, err := g.validateTemplates(allTemplates)
+	if err != nil {
+		var verr *ValidationError
+		if !g.config.SkipInvalid || !errors.As(err, &verr) {
+			return err
+		}
+		allTemplates = skipInvalid(allTemplates, verr)
+		if len(allTemplates) == 0 {
+			return fmt.Errorf("no valid templates left: %w", err)
+		}
+		skipped = true
+	}
+	if err := g.checkDuplicateDefines(allTemplates, defines); err != nil {
+		return err
+	}
+
+	// Generate the output file
+	if err := g.generateFile(allTemplates, skipped); err != nil {
+		return err
+	}
+
+	if g.config.EmitManifest {
+		return g.writeManifest(allTemplates)
+	}
+	return nil
+}
+
+// generatePerDir runs a separate generation for every immediate
+// subdirectory of the root that contains templates, writing its
+// templates_gen.go into that subdirectory with the directory name as package.
+// Each package goes through the full Generate, so validation and the
+// duplicate define check apply to its own template set.
+func (g *TemplatesGenerator) generatePerDir() error {
+	if g.config.Manifest != "" {
+		return fmt.Errorf("the package_per_dir and manifest options cannot be combined")
+	}
+
+	subs, err := g.packageDirs()
+	if err != nil {
+		return err
+	}
+
+	generated := 0
+	for _, sub := range subs {
+		if sub.nameErr != nil {
+			// Only worth mentioning if the directory holds templates.
+			if templates, _ := sub.discover(); len(templates) > 0 {
+				fmt.Fprintf(os.Stderr, "warning: skipping %s: %v\n", sub.config.Root, sub.nameErr)
+			}
+			continue
+		}
+		err := sub.Generate()
+		if errors.Is(err, ErrNoTemplates) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("generating package %s: %w", sub.config.Package, err)
+		}
+		generated++
+	}
+
+	if generated == 0 {
+		return ErrNoTemplates
+	}
+	return nil
+}
+
+// Outputs returns the path of every file Generate would write: the
+// templates_gen.go of each package, the group files of split_by_dir and the
+// manifest of emit_manifest. Finding the per-directory packages and groups
+// requires discovering the templates.
+func (g *TemplatesGenerator) Outputs() ([]string, error) {
+	if g.config.PackagePerDir {
+		subs, err := g.packageDirs()
+		if err != nil {
+			return nil, err
+		}
+		var outputs []string
+		for _, sub := range subs {
+			if sub.nameErr != nil {
+				continue
+			}
+			if templates, _ := sub.discover(); len(templates) == 0 {
+				continue
+			}
+			subOutputs, err := sub.Outputs()
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, subOutputs...)
+		}
+		return outputs, nil
+	}
+
+	root := g.config.Root
+	if root == "" {
+		root = "."
+	}
+
+	outputs := []string{filepath.Join(root, "templates_gen.go")}
+	if g.config.EmitManifest {
+		outputs = append(outputs, filepath.Join(root, "templates_manifest.json"))
+	}
+	if g.config.SplitByDir {
+		templates, err := g.discover()
+		if err != nil {
+			return nil, err
+		}
+		groups, err := g.groupTemplates(templates)
+		if err != nil {
+			return nil, err
+		}
+		for name := range groups {
+			outputs = append(outputs, filepath.Join(root, "templates_"+name+"_gen.go"))
+		}
+	}
+	return outputs, nil
+}
+
+// CheckOutputs fails if two template sets, e.g. from included config files,
+// would write the same file.
+func CheckOutputs(sets []*config.TemplatesConfig) error {
+	owners := make(map[string]bool)
+	for _, set := range sets {
+		outputs, err := NewTemplatesGenerator(set).Outputs()
+		if err != nil {
+			return err
+		}
+		for _, output := range outputs {
+			abs, err := filepath.Abs(output)
+			if err != nil {
+				return err
+			}
+			if owners[abs] {
+				return fmt.Errorf("more than one templates section writes %s", output)
+			}
+			owners[abs] = true
+		}
+	}
+	return nil
+}
+
+// packageDirs returns a generator for each immediate subdirectory of the
+// root, configured like g but rooted at the subdirectory. Hidden directories
+// and those the go tool ignores (vendor, testdata, _*) are left out. Dirs
+// patterns are resolved inside each subdirectory, and subdirectories missing
+// some of them are tolerated. A directory whose name yields no package name
+// gets a generator with nameErr set.
+func (g *TemplatesGenerator) packageDirs() ([]*TemplatesGenerator, error) {
+	root := g.config.Root
+	if root == "" {
+		root = "."
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*TemplatesGenerator
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+			name == "vendor" || name == "testdata" {
+			continue
+		}
+
+		pkg, err := packageName(name)
+
+		cfg := *g.config
+		cfg.Root = filepath.Join(root, name)
+		cfg.Package = pkg
+		cfg.PackagePerDir = false
+		cfg.AllowMissingDirs = true
+		subs = append(subs, &TemplatesGenerator{config: &cfg, packageDir: true, nameErr: err})
+	}
+	return subs, nil
+}
+
+// packageName derives a Go package name from a directory name by lowercasing
+// it and dropping everything but letters and digits, so "Email-Templates"
+// becomes "emailtemplates".
+func packageName(dir string) (string, error) {
+	name := nonPackageChars.ReplaceAllString(strings.ToLower(dir), "")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		return "", fmt.Errorf("cannot derive a package name from directory %q", dir)
+	}
+	return name, nil
+}
+
+// nonPackageChars matches the characters packageName drops.
+var nonPackageChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// discover returns the templates listed in the manifest file, or else those
+// found by scanning all configured dirs, rejecting constant name collisions.
+func (g *TemplatesGenerator) discover() ([]TemplateInfo, error) {
 	var allTemplates []TemplateInfo
-	seenNames := make(map[string]string) // constName -> relPath for duplicate detection
 
-	for _, dir := range g.config.Dirs {
-		templates, err := g.scanDir(dir)
+	if g.config.Manifest != "" {
+		templates, err := g.readManifest()
 		if err != nil {
-			return fmt.Errorf("scanning %s: %w", dir, err)
+			return nil, fmt.Errorf("reading manifest %s: %w", g.config.Manifest, err)
 		}
+		allTemplates = templates
+	} else {
+		for _, dir := range g.config.Dirs {
+			exists, err := g.dirExists(dir)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				if g.config.AllowMissingDirs {
+					continue
+				}
+				return nil, fmt.Errorf("configured dir %s does not exist", patternBaseDir(dir))
+			}
 
-		// Check for duplicates
-		for _, t := range templates {
-			if existing, ok := seenNames[t.ConstName]; ok {
-				return fmt.Errorf("duplicate constant name %q from %q and %q", t.ConstName, existing, t.RelPath)
+			templates, err := g.scanDir(dir)
+			if err != nil {
+				return nil, fmt.Errorf("scanning %s: %w", dir, err)
 			}
-			seenNames[t.ConstName] = t.RelPath
+			allTemplates = append(allTemplates, templates...)
 		}
+	}
 
-		allTemplates = append(allTemplates, templates...)
+	// Check for duplicates
+	seenNames := make(map[string]string)   // constName -> relPath for duplicate detection
+	seenRuntime := make(map[string]string) // name -> relPath, base and flat names may collide
+	for _, t := range allTemplates {
+		if existing, ok := seenNames[t.ConstName]; ok {
+			return nil, fmt.Errorf("duplicate constant name %q from %q and %q", t.ConstName, existing, t.RelPath)
+		}
+		seenNames[t.ConstName] = t.RelPath
+		if existing, ok := seenRuntime[t.Name]; ok {
+			return nil, fmt.Errorf("duplicate template name %q from %q and %q", t.Name, existing, t.RelPath)
+		}
+		seenRuntime[t.Name] = t.RelPath
 	}
 
 	if len(allTemplates) == 0 {
-		return fmt.Errorf("no templates found in configured dirs")
+		return nil, ErrNoTemplates
 	}
 
-	// Validate templates syntax
-	if err := g.validateTemplates(allTemplates); err != nil {
-		return err
+	if err := g.assignDataTypes(allTemplates); err != nil {
+		return nil, err
 	}
+	return allTemplates, nil
+}
 
-	// Generate the output file
-	return g.generateFile(allTemplates)
+// assignDataTypes sets the DataType of each template matched by a data_types
+// glob. A template matched by more than one glob is an error.
+func (g *TemplatesGenerator) assignDataTypes(templates []TemplateInfo) error {
+	for i := range templates {
+		var matchedPattern string
+		for pattern, dataType := range g.config.DataTypes {
+			match, err := path.Match(pattern, templates[i].RelPath)
+			if err != nil {
+				return fmt.Errorf("invalid data_types pattern %q: %w", pattern, err)
+			}
+			if !match {
+				continue
+			}
+			if matchedPattern != "" {
+				return fmt.Errorf("template %s matches data_types patterns %q and %q", templates[i].RelPath, matchedPattern, pattern)
+			}
+			matchedPattern = pattern
+			templates[i].DataType = dataType
+		}
+	}
+	return nil
+}
+
+// readManifest reads the manifest file, one template path per line relative
+// to the root. Blank lines and lines starting with # are ignored; absolute
+// paths and paths leading out of the root are rejected, since go:embed could
+// not embed them.
+func (g *TemplatesGenerator) readManifest() ([]TemplateInfo, error) {
+	root := g.config.Root
+	if root == "" {
+		root = "."
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, g.config.Manifest))
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []TemplateInfo
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		relPath := path.Clean(normalizeSlashes(line))
+		if path.IsAbs(relPath) || filepath.IsAbs(line) || filepath.VolumeName(line) != "" {
+			return nil, fmt.Errorf("line %d: listed template %s must be relative to the root", i+1, line)
+		}
+		if relPath == ".." || strings.HasPrefix(relPath, "../") {
+			return nil, fmt.Errorf("line %d: listed template %s is outside the root", i+1, line)
+		}
+		info, err := os.Stat(filepath.Join(root, relPath))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: listed template %s: %w", i+1, relPath, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("line %d: listed template %s is a directory", i+1, relPath)
+		}
+
+		templates = append(templates, TemplateInfo{
+			FileName:  path.Base(relPath),
+			RelPath:   relPath,
+			ConstName: g.constName(relPath),
+			Name:      g.templateName(relPath),
+		})
+	}
+	return templates, nil
 }
 
 // scanDir scans a directory using glob pattern for template files.
@@ -97,11 +436,12 @@ func (g *TemplatesGenerator) scanDir(pattern string) ([]TemplateInfo, error) {
 				return nil
 			}
 
-			relPath, _ := filepath.Rel(root, path)
+			relPath := relativeTemplatePath(root, path)
 			templates = append(templates, TemplateInfo{
 				FileName:  d.Name(),
 				RelPath:   relPath,
-				ConstName: pathToPascalCase(relPath),
+				ConstName: g.constName(relPath),
+				Name:      g.templateName(relPath),
 			})
 			return nil
 		})
@@ -125,11 +465,12 @@ func (g *TemplatesGenerator) scanDir(pattern string) ([]TemplateInfo, error) {
 				continue
 			}
 
-			relPath, _ := filepath.Rel(root, path)
+			relPath := relativeTemplatePath(root, path)
 			templates = append(templates, TemplateInfo{
 				FileName:  filepath.Base(path),
 				RelPath:   relPath,
-				ConstName: pathToPascalCase(relPath),
+				ConstName: g.constName(relPath),
+				Name:      g.templateName(relPath),
 			})
 		}
 	}
@@ -137,6 +478,45 @@ func (g *TemplatesGenerator) scanDir(pattern string) ([]TemplateInfo, error) {
 	return templates, nil
 }
 
+// relativeTemplatePath returns path relative to root with forward slashes, so
+// generated constants match embed.FS and template names on every platform.
+func relativeTemplatePath(root, path string) string {
+	relPath, _ := filepath.Rel(root, path)
+	return normalizeSlashes(relPath)
+}
+
+// normalizeSlashes converts Windows path separators to forward slashes.
+func normalizeSlashes(path string) string {
+	return strings.ReplaceAll(filepath.ToSlash(path), `\`, "/")
+}
+
+// patternBaseDir returns the leading directories of pattern that contain no
+// glob metacharacters, e.g. "templates/emails" for "templates/emails/*.tmpl".
+func patternBaseDir(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for dir != "." && dir != "/" && strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// dirExists reports whether the base directory of a configured pattern exists.
+func (g *TemplatesGenerator) dirExists(pattern string) (bool, error) {
+	root := g.config.Root
+	if root == "" {
+		root = "."
+	}
+
+	info, err := os.Stat(filepath.Join(root, patternBaseDir(pattern)))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
 // splitRecursivePattern splits "templates/**/*.tmpl" into "templates" and "*.tmpl"
 func splitRecursivePattern(pattern string) (baseDir, filePattern string) {
 	idx := strings.Index(pattern, "**")
@@ -160,9 +540,56 @@ func splitRecursivePattern(pattern string) (baseDir, filePattern string) {
 	return baseDir, filePattern
 }
 
-// validateTemplates checks template syntax by parsing them.
-func (g *TemplatesGenerator) validateTemplates(templates []TemplateInfo) error {
-	var errs []error
+// TemplateError is a problem with a single template file. Line is the
+// 1-based line reported by the template parser, or 0 when unknown.
+type TemplateError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *TemplateError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError collects every TemplateError found by a generation run,
+// so all broken templates are reported at once.
+type ValidationError struct {
+	Errors []*TemplateError
+}
+
+// Error lists one template problem per line.
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("template validation failed:")
+	for _, err := range e.Errors {
+		b.WriteString("\n  ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the individual template errors for errors.Is and errors.As.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// validateTemplates checks template syntax by parsing them. It returns the
+// names each valid template {{define}}s, keyed by RelPath.
+func (g *TemplatesGenerator) validateTemplates(templates []TemplateInfo) (map[string][]string, error) {
+	var errs []*TemplateError
+	defines := make(map[string][]string, len(templates))
 
 	root := g.config.Root
 	if root == "" {
@@ -173,24 +600,187 @@ func (g *TemplatesGenerator) validateTemplates(templates []TemplateInfo) error {
 		fullPath := filepath.Join(root, t.RelPath)
 		content, err := os.ReadFile(fullPath)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("reading %s: %w", t.RelPath, err))
+			errs = append(errs, &TemplateError{Path: t.RelPath, Err: err})
 			continue
 		}
+		content = g.stripBOM(content)
 
-		_, err = template.New(t.FileName).Parse(string(content))
+		if len(content) == 0 {
+			if g.config.ErrorEmpty {
+				errs = append(errs, &TemplateError{Path: t.RelPath, Err: errors.New("empty template")})
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "warning: empty template %s\n", t.RelPath)
+		}
+
+		tmpl := template.New(t.FileName)
+		if g.config.Builtins {
+			tmpl = tmpl.Funcs(rumtpl.Builtins())
+		}
+		parsed, err := tmpl.Parse(string(content))
 		if err != nil {
-			errs = append(errs, fmt.Errorf("parsing %s: %w", t.RelPath, err))
+			errs = append(errs, parseError(t, err))
+			continue
+		}
+		defines[t.RelPath] = definedNames(parsed, string(content))
+		// Front matter is a comment, so it parses either way; the manager
+		// rejects malformed front matter at load time.
+		if _, _, err := rumtpl.ParseFrontMatter(content); err != nil {
+			errs = append(errs, &TemplateError{Path: t.RelPath, Err: err})
 		}
 	}
 
 	if len(errs) > 0 {
-		return fmt.Errorf("template validation failed:\n%v", errs)
+		return defines, &ValidationError{Errors: errs}
+	}
+	return defines, nil
+}
+
+// stripBOM drops a leading UTF-8 byte order mark from template content the
+// way the manager does at load time, unless keep_bom is set.
+func (g *TemplatesGenerator) stripBOM(content []byte) []byte {
+	if g.config.KeepBOM {
+		return content
+	}
+	return rumtpl.StripBOM(content)
+}
+
+// definedNames returns the sorted names tmpl, freshly parsed from text,
+// {{define}}s. {{block}} is left out on purpose: its body is a default meant
+// to be replaced by a define elsewhere. The parser records a block as a
+// define plus a {{template}} call, so a block is recognised as a call whose
+// template body starts right after the call's own action.
+func definedNames(tmpl *template.Template, text string) []string {
+	trees := make(map[string]*parse.Tree)
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && t.Name() != tmpl.Name() {
+			trees[t.Name()] = t.Tree
+		}
+	}
+
+	blocks := make(map[string]bool)
+	var walk func(parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.TemplateNode:
+			body, ok := trees[n.Name]
+			if ok && body.Root != nil && body.Root.Pos > n.Pos &&
+				int(body.Root.Pos) <= len(text) && !strings.Contains(text[n.Pos:body.Root.Pos], "{{") {
+				blocks[n.Name] = true
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	if tmpl.Tree != nil {
+		walk(tmpl.Tree.Root)
+	}
+	for _, tree := range trees {
+		walk(tree.Root)
+	}
+
+	var names []string
+	for name := range trees {
+		if !blocks[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkDuplicateDefines reports names that more than one template file
+// defines, since the manager parses all files into one set where the last
+// definition silently wins. Depending on duplicate_defines the conflicts are
+// printed as warnings, returned as an error, or ignored.
+func (g *TemplatesGenerator) checkDuplicateDefines(templates []TemplateInfo, defines map[string][]string) error {
+	if g.config.DuplicateDefines == config.DuplicateDefinesIgnore {
+		return nil
+	}
+
+	var names []string
+	files := make(map[string][]string) // define name -> files defining it
+	for _, t := range templates {
+		for _, name := range defines[t.RelPath] {
+			if files[name] == nil {
+				names = append(names, name)
+			}
+			files[name] = append(files[name], t.RelPath)
+		}
+	}
+
+	var conflicts []string
+	for _, name := range names {
+		if len(files[name]) > 1 {
+			conflicts = append(conflicts, fmt.Sprintf("%q is defined in %s", name, strings.Join(files[name], ", ")))
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	if g.config.DuplicateDefines == config.DuplicateDefinesError {
+		return fmt.Errorf("duplicate template definitions: %s", strings.Join(conflicts, "; "))
+	}
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "warning: duplicate template definition: %s\n", c)
 	}
 	return nil
 }
 
-// generateFile creates the generated Go file.
-func (g *TemplatesGenerator) generateFile(templates []TemplateInfo) error {
+// skipInvalid warns about every template in verr and returns templates
+// without them, followed by a summary of how many were skipped.
+func skipInvalid(templates []TemplateInfo, verr *ValidationError) []TemplateInfo {
+	invalid := make(map[string]bool, len(verr.Errors))
+	for _, e := range verr.Errors {
+		fmt.Fprintf(os.Stderr, "warning: skipping invalid template %v\n", e)
+		invalid[e.Path] = true
+	}
+
+	var valid []TemplateInfo
+	for _, t := range templates {
+		if !invalid[t.RelPath] {
+			valid = append(valid, t)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "warning: skipped %d of %d templates\n", len(templates)-len(valid), len(templates))
+	return valid
+}
+
+// parseError converts a text/template parse error, formatted as
+// "template: <name>:<line>: <msg>", into a TemplateError with the line split out.
+func parseError(t TemplateInfo, err error) *TemplateError {
+	rest, ok := strings.CutPrefix(err.Error(), "template: "+t.FileName+":")
+	if !ok {
+		return &TemplateError{Path: t.RelPath, Err: err}
+	}
+	lineStr, msg, ok := strings.Cut(rest, ": ")
+	line, convErr := strconv.Atoi(lineStr)
+	if !ok || convErr != nil {
+		return &TemplateError{Path: t.RelPath, Err: err}
+	}
+	return &TemplateError{Path: t.RelPath, Line: line, Err: errors.New(msg)}
+}
+
+// generateFile creates the generated Go file. With exact set, or a manifest,
+// only the given templates are embedded instead of everything the dirs
+// patterns match, so templates left out (e.g. by skip_invalid) are not
+// parsed at runtime either.
+func (g *TemplatesGenerator) generateFile(templates []TemplateInfo, exact bool) error {
 	root := g.config.Root
 	if root == "" {
 		root = "."
@@ -204,29 +794,101 @@ func (g *TemplatesGenerator) generateFile(templates []TemplateInfo) error {
 		return fmt.Errorf("creating output directory: %w", err)
 	}
 
-	// Collect unique directories for embed
-	embedDirs := make(map[string]bool)
-	for _, dir := range g.config.Dirs {
-		// Convert pattern to embed-compatible format
-		embedDir := strings.ReplaceAll(dir, "**", "*")
-		embedDirs[embedDir] = true
+	var embedPatterns []string
+	if g.config.Manifest != "" || exact {
+		// Embed exactly the listed files
+		for _, t := range templates {
+			embedPatterns = append(embedPatterns, t.RelPath)
+		}
+	} else {
+		// Collect unique directories for embed
+		embedDirs := make(map[string]bool)
+		for _, dir := range g.config.Dirs {
+			// Missing dirs were skipped by discover (allow_missing_dirs)
+			exists, err := g.dirExists(dir)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				continue
+			}
+			// Convert pattern to embed-compatible format
+			embedDir := strings.ReplaceAll(dir, "**", "*")
+			embedDirs[embedDir] = true
+		}
+
+		for dir := range embedDirs {
+			embedPatterns = append(embedPatterns, dir)
+		}
+	}
+
+	assetPatterns, err := g.assetPatterns(root)
+	if err != nil {
+		return err
 	}
 
-	var embedPatterns []string
-	for dir := range embedDirs {
-		embedPatterns = append(embedPatterns, dir)
+	for i, t := range templates {
+		content, err := os.ReadFile(filepath.Join(root, t.RelPath))
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", t.RelPath, err)
+		}
+		templates[i].Checksum = rumtpl.Checksum(content)
+	}
+
+	// Only files the manager parses are walked by VerifyIntegrity, so
+	// checksums of other discovered files would be reported as missing.
+	var checksummed []TemplateInfo
+	for _, t := range templates {
+		if match, _ := path.Match(managerPattern, t.FileName); match {
+			checksummed = append(checksummed, t)
+		}
 	}
 
 	data := struct {
-		Package       string
-		Templates     []TemplateInfo
-		EmbedPatterns []string
-		Dirs          []string
+		Package         string
+		Templates       []TemplateInfo
+		Checksummed     []TemplateInfo
+		ManagerPattern  string
+		EmbedPatterns   []string
+		AssetPatterns   []string
+		Assets          bool
+		Dirs            []string
+		Builtins        bool
+		Lazy            bool
+		Constructor     bool
+		DataImports     []string
+		ExposeFS        bool
+		SplitByDir      bool
+		RenameTemplates bool
+		KeepBOM         bool
 	}{
-		Package:       g.config.Package,
-		Templates:     templates,
-		EmbedPatterns: embedPatterns,
-		Dirs:          g.config.Dirs,
+		Package:        g.config.Package,
+		Templates:      templates,
+		Checksummed:    checksummed,
+		ManagerPattern: managerPattern,
+		EmbedPatterns:  embedPatterns,
+		AssetPatterns:  assetPatterns,
+		Assets:         len(g.config.Assets) > 0,
+		Dirs:           g.config.Dirs,
+		Builtins:       g.config.Builtins,
+		Lazy:           g.config.Lazy,
+		Constructor:    g.config.Constructor,
+		DataImports:    g.config.DataImports,
+		ExposeFS:       g.config.ExposeFS,
+		SplitByDir:     g.config.SplitByDir,
+		KeepBOM:        g.config.KeepBOM,
+
+		RenameTemplates: g.config.NameStyle == config.NameStyleBase || g.config.NameStyle == config.NameStyleFlat,
+	}
+
+	// Group collisions are checked before anything is written, so a failed
+	// run leaves the previous output intact.
+	var groups map[string][]TemplateInfo
+	if g.config.SplitByDir {
+		groups, err = g.groupTemplates(templates)
+		if err != nil {
+			return err
+		}
 	}
 
 	var buf bytes.Buffer
@@ -238,15 +900,245 @@ func (g *TemplatesGenerator) generateFile(templates []TemplateInfo) error {
 		return fmt.Errorf("writing output file: %w", err)
 	}
 
+	// Group files from a previous split run would declare the constants
+	// twice, so they are removed before splitting again or when no longer
+	// splitting. Only files carrying rum's generated header are touched.
+	stale, err := generatedGroupFiles(root)
+	if err != nil {
+		return err
+	}
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("removing %s: %w", path, err)
+		}
+	}
+
+	if g.config.SplitByDir {
+		fmt.Printf("Generated %s\n", outputFile)
+		return g.writeGroupFiles(root, groups)
+	}
 	fmt.Printf("Generated %s with %d templates\n", outputFile, len(templates))
 	return nil
 }
 
+// managerPattern is the file name pattern the generated manager parses.
+const managerPattern = "*.tmpl"
+
+// generatedHeader starts every file rum generates.
+const generatedHeader = "// Code generated by rum. DO NOT EDIT."
+
+// generatedGroupFiles returns the templates_*_gen.go files in root that rum
+// generated, leaving out user files that merely match the name.
+func generatedGroupFiles(root string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(root, "templates_*_gen.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var generated []string
+	for _, path := range matches {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if bytes.HasPrefix(content, []byte(generatedHeader)) {
+			generated = append(generated, path)
+		}
+	}
+	return generated, nil
+}
+
+// assetPatterns converts the assets globs to embed patterns, checking that
+// each matches at least one file since go:embed rejects empty patterns. In a
+// package_per_dir package, patterns matching nothing are left out instead.
+func (g *TemplatesGenerator) assetPatterns(root string) ([]string, error) {
+	var patterns []string
+	for _, asset := range g.config.Assets {
+		pattern := strings.ReplaceAll(asset, "**", "*")
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid assets pattern %q: %w", asset, err)
+		}
+		if len(matches) == 0 {
+			if g.packageDir {
+				continue
+			}
+			return nil, fmt.Errorf("assets pattern %q matches no files", asset)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// groupTemplates splits templates by top-level directory, after
+// strip_prefixes is applied, for split_by_dir. Templates directly under the
+// root form the "root" group. It fails when two directories map to the same
+// group file name.
+func (g *TemplatesGenerator) groupTemplates(templates []TemplateInfo) (map[string][]TemplateInfo, error) {
+	groups := make(map[string][]TemplateInfo)
+	dirs := make(map[string]string) // group name -> directory it came from, "" for the root
+	for _, t := range templates {
+		path := trimPrefixes(t.RelPath, g.stripPrefixes())
+		dir, _, found := strings.Cut(path, "/")
+		if !found {
+			dir = ""
+		}
+		group := groupName(path)
+		if prev, ok := dirs[group]; ok && prev != dir {
+			return nil, fmt.Errorf("directories %q and %q would both be written to templates_%s_gen.go", displayDir(prev), displayDir(dir), group)
+		}
+		dirs[group] = dir
+		groups[group] = append(groups[group], t)
+	}
+	return groups, nil
+}
+
+// displayDir names a group's directory in errors, "." for the root.
+func displayDir(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// writeGroupFiles writes one constants file per group from groupTemplates.
+func (g *TemplatesGenerator) writeGroupFiles(root string, groups map[string][]TemplateInfo) error {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data := struct {
+			Package   string
+			Templates []TemplateInfo
+		}{
+			Package:   g.config.Package,
+			Templates: groups[name],
+		}
+
+		var buf bytes.Buffer
+		if err := outputTemplate.ExecuteTemplate(&buf, "group", data); err != nil {
+			return fmt.Errorf("executing template: %w", err)
+		}
+
+		outputFile := filepath.Join(root, "templates_"+name+"_gen.go")
+		if err := os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("writing output file: %w", err)
+		}
+		fmt.Printf("Generated %s with %d templates\n", outputFile, len(groups[name]))
+	}
+	return nil
+}
+
+// groupName returns the file-name-safe top-level directory of relPath, or
+// "root" for templates directly under the root.
+func groupName(relPath string) string {
+	dir, _, found := strings.Cut(relPath, "/")
+	if !found {
+		return "root"
+	}
+	return strings.ToLower(unsafeGroupChars.ReplaceAllString(dir, "_"))
+}
+
+// unsafeGroupChars matches runs of characters groupName replaces.
+var unsafeGroupChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// manifestEntry describes one template in templates_manifest.json.
+type manifestEntry struct {
+	Path      string `json:"path"`
+	ConstName string `json:"const_name"`
+	Size      int64  `json:"size"`
+}
+
+// writeManifest writes templates_manifest.json next to templates_gen.go.
+func (g *TemplatesGenerator) writeManifest(templates []TemplateInfo) error {
+	root := g.config.Root
+	if root == "" {
+		root = "."
+	}
+
+	entries := make([]manifestEntry, 0, len(templates))
+	for _, t := range templates {
+		info, err := os.Stat(filepath.Join(root, t.RelPath))
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", t.RelPath, err)
+		}
+		entries = append(entries, manifestEntry{
+			Path:      t.RelPath,
+			ConstName: t.ConstName,
+			Size:      info.Size(),
+		})
+	}
+
+	content, err := json.MarshalIndent(struct {
+		Templates []manifestEntry `json:"templates"`
+	}{entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	outputFile := filepath.Join(root, "templates_manifest.json")
+	if err := os.WriteFile(outputFile, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	fmt.Printf("Generated %s\n", outputFile)
+	return nil
+}
+
+// constName derives the Go constant name for a template path from its
+// runtime name, prefixing names whose leading segment starts with a digit so
+// they remain valid identifiers.
+func (g *TemplatesGenerator) constName(relPath string) string {
+	name := pathToPascalCaseStripping(g.templateName(relPath), g.stripPrefixes())
+	if name == "" || !unicode.IsDigit(rune(name[0])) {
+		return name
+	}
+
+	prefix := g.config.NumericPrefix
+	if prefix == "" {
+		prefix = defaultNumericPrefix
+	}
+	return prefix + name
+}
+
+// templateName returns the name the template at relPath is registered under
+// at runtime: the path itself, its file name for name_style base, or for flat
+// the path without strip_prefixes and with "/" replaced by "_".
+func (g *TemplatesGenerator) templateName(relPath string) string {
+	switch g.config.NameStyle {
+	case config.NameStyleBase:
+		return path.Base(relPath)
+	case config.NameStyleFlat:
+		return strings.ReplaceAll(trimPrefixes(relPath, g.stripPrefixes()), "/", "_")
+	}
+	return relPath
+}
+
+// stripPrefixes returns the configured strip_prefixes or the defaults.
+func (g *TemplatesGenerator) stripPrefixes() []string {
+	if g.config.StripPrefixes == nil {
+		return defaultStripPrefixes
+	}
+	return g.config.StripPrefixes
+}
+
+// defaultStripPrefixes are the leading path segments dropped from constant
+// names when strip_prefixes is not configured.
+var defaultStripPrefixes = config.DefaultStripPrefixes
+
 // pathToPascalCase converts a path like "templates/openapi/api.template.yaml.tmpl" to "OpenapiApiTemplate"
 func pathToPascalCase(path string) string {
+	return pathToPascalCaseStripping(path, defaultStripPrefixes)
+}
+
+// pathToPascalCaseStripping is pathToPascalCase with a custom list of
+// leading path prefixes to remove, applied in order.
+func pathToPascalCaseStripping(path string, prefixes []string) string {
 	// Remove common prefixes
-	path = strings.TrimPrefix(path, "templates/")
-	path = strings.TrimPrefix(path, "template/")
+	path = trimPrefixes(path, prefixes)
 
 	// Remove extensions
 	path = strings.TrimSuffix(path, ".tmpl")
@@ -271,6 +1163,17 @@ func pathToPascalCase(path string) string {
 	return strings.Join(words, "")
 }
 
+// trimPrefixes removes the leading path prefixes from path, in order.
+func trimPrefixes(path string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		path = strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
 var outputTemplate = template.Must(template.New("output").Parse(`// Code generated by rum. DO NOT EDIT.
 //go:generate rum gen
 
@@ -278,30 +1181,122 @@ package {{.Package}}
 
 import (
 	"embed"
+{{- if or .ExposeFS .Assets}}
+	"io/fs"
+{{- end}}
+{{- if .Lazy}}
+	"sync"
+{{- end}}
 
 	rumtpl "github.com/4Sigma/rum/template_manager"
+{{- range .DataImports}}
+	"{{.}}"
+{{- end}}
 )
 
 {{range .EmbedPatterns}}//go:embed {{.}}
 {{end}}var templatesFS embed.FS
+{{if .ExposeFS}}
+// Templates returns read-only access to the embedded template files.
+func Templates() fs.FS {
+	return templatesFS
+}
+{{end}}{{if .Assets}}
+{{range .AssetPatterns}}//go:embed {{.}}
+{{end}}var assetsFS embed.FS
 
+// Assets returns read-only access to the embedded asset files, e.g. for
+// http.FileServerFS.
+func Assets() fs.FS {
+	return assetsFS
+}
+{{end}}
 // TemplateName is a type-safe template identifier.
 type TemplateName = rumtpl.Name
-
-const (
+{{if .RenameTemplates}}
+// templateNames maps embedded template paths to their name_style names.
+var templateNames = map[string]TemplateName{
 {{- range .Templates}}
-	{{.ConstName}} TemplateName = "{{.RelPath}}"
+	"{{.RelPath}}": {{.ConstName}},
+{{- end}}
+}
+{{end}}
+// templateChecksums holds the SHA-256 of each template file at generation
+// time, checked by Manager.VerifyIntegrity.
+var templateChecksums = map[string]string{
+{{- range .Checksummed}}
+	"{{.RelPath}}": "{{.Checksum}}",
 {{- end}}
+}
+{{if not .SplitByDir}}
+{{template "consts" .}}
+{{end}}{{range .Templates}}{{if .DataType}}
+// Render{{.ConstName}} renders {{.ConstName}} with typed data.
+func Render{{.ConstName}}(r rumtpl.Renderer, data {{.DataType}}) ([]byte, error) {
+	return r.Render({{.ConstName}}, data)
+}
+{{end}}{{end}}{{if .Lazy}}
+var (
+	managerOnce sync.Once
+	manager     *rumtpl.Manager
+	managerErr  error
 )
 
+// Manager returns the template manager, parsing the templates on first use.
+func Manager() (*rumtpl.Manager, error) {
+	managerOnce.Do(func() {
+		manager, managerErr = loadManager()
+	})
+	return manager, managerErr
+}
+
+// Render renders the named template, initializing the manager on first use.
+func Render(name TemplateName, data any) ([]byte, error) {
+	m, err := Manager()
+	if err != nil {
+		return nil, err
+	}
+	return m.Render(name, data)
+}
+{{else if .Constructor}}
+// NewManager parses the embedded templates into a new template manager.
+func NewManager() (*rumtpl.Manager, error) {
+	return loadManager()
+}
+{{else}}
 // Manager is the template manager instance.
 var Manager *rumtpl.Manager
 
 func init() {
 	var err error
-	Manager, err = rumtpl.NewManagerFromFS(templatesFS, "*.tmpl")
+	Manager, err = loadManager()
 	if err != nil {
 		panic("rum: failed to initialize template manager: " + err.Error())
 	}
 }
-`))
+{{end}}
+// loadManager parses the embedded templates and records their checksums.
+func loadManager() (*rumtpl.Manager, error) {
+	m, err := {{template "newManager" .}}
+	if err != nil {
+		return nil, err
+	}
+	m.SetChecksums(templateChecksums)
+	return m, nil
+}
+{{define "consts"}}const (
+{{- range .Templates}}
+	{{.ConstName}} TemplateName = "{{.Name}}"
+{{- end}}
+){{end}}
+{{- define "group"}}// Code generated by rum. DO NOT EDIT.
+
+package {{.Package}}
+
+{{template "consts" .}}
+{{end}}
+{{- define "newManager"}}{{if .RenameTemplates -}}
+rumtpl.NewManagerFromFSNamed(templatesFS, "{{.ManagerPattern}}", templateNames, {{if .Builtins}}rumtpl.Builtins(){{else}}nil{{end}}{{if .KeepBOM}}, rumtpl.KeepBOM(){{end}})
+{{- else -}}
+rumtpl.{{if .Builtins}}NewManagerFromFSWithBuiltins{{else}}NewManagerFromFS{{end}}(templatesFS, "{{.ManagerPattern}}"{{if .KeepBOM}}, rumtpl.KeepBOM(){{end}})
+{{- end}}{{end}}`))
@@ -1,12 +1,21 @@
 package generator
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/4Sigma/rum/internal/config"
+	rumtpl "github.com/4Sigma/rum/template_manager"
 )
 
 func TestPathToPascalCase(t *testing.T) {
@@ -31,6 +40,54 @@ func TestPathToPascalCase(t *testing.T) {
 	}
 }
 
+func TestConstNameNumericPrefix(t *testing.T) {
+	tests := []struct {
+		input  string
+		prefix string
+		want   string
+	}{
+		{"templates/v2/users.tmpl", "", "V2Users"},
+		{"templates/2fa/setup.tmpl", "", "N2faSetup"},
+		{"templates/404.html.tmpl", "", "N404"},
+		{"templates/2fa/setup.tmpl", "Tpl", "Tpl2faSetup"},
+		{"templates/pages/2fa.tmpl", "", "Pages2fa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			gen := NewTemplatesGenerator(&config.TemplatesConfig{NumericPrefix: tt.prefix})
+			got := gen.constName(tt.input)
+			if got != tt.want {
+				t.Errorf("constName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSlashes(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      string
+		wantConst string
+	}{
+		{`templates\pages\home.html.tmpl`, "templates/pages/home.html.tmpl", "PagesHome"},
+		{"templates/pages/home.html.tmpl", "templates/pages/home.html.tmpl", "PagesHome"},
+		{`templates/emails\welcome.tmpl`, "templates/emails/welcome.tmpl", "EmailsWelcome"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := normalizeSlashes(tt.input)
+			if got != tt.want {
+				t.Errorf("normalizeSlashes(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if name := pathToPascalCase(got); name != tt.wantConst {
+				t.Errorf("pathToPascalCase(%q) = %q, want %q", got, name, tt.wantConst)
+			}
+		})
+	}
+}
+
 func TestSplitRecursivePattern(t *testing.T) {
 	tests := []struct {
 		pattern     string
@@ -171,6 +228,205 @@ func TestGenerateInvalidTemplate(t *testing.T) {
 	}
 }
 
+func TestGenerateValidationErrorListsAllTemplates(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir := filepath.Join(dir, "templates")
+	os.MkdirAll(templatesDir, 0755)
+
+	os.WriteFile(filepath.Join(templatesDir, "a.html.tmpl"), []byte("ok\n{{.Invalid"), 0644)
+	os.WriteFile(filepath.Join(templatesDir, "b.html.tmpl"), []byte("{{end}}"), 0644)
+	os.WriteFile(filepath.Join(templatesDir, "c.html.tmpl"), []byte("fine"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:    dir,
+		Package: "main",
+		Dirs:    []string{"templates/*.tmpl"},
+	}
+
+	err := NewTemplatesGenerator(cfg).Generate()
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+	if len(verr.Errors) != 2 {
+		t.Fatalf("expected 2 template errors, got %d: %v", len(verr.Errors), verr)
+	}
+
+	want := []struct {
+		path string
+		line int
+	}{
+		{"templates/a.html.tmpl", 2},
+		{"templates/b.html.tmpl", 1},
+	}
+	for i, w := range want {
+		got := verr.Errors[i]
+		if got.Path != w.path || got.Line != w.line {
+			t.Errorf("error %d = %s:%d, want %s:%d", i, got.Path, got.Line, w.path, w.line)
+		}
+	}
+
+	if lines := strings.Split(err.Error(), "\n"); len(lines) != 3 {
+		t.Errorf("expected a header and one line per template, got:\n%s", err)
+	}
+	if unwrapped := verr.Unwrap(); len(unwrapped) != 2 {
+		t.Errorf("Unwrap() returned %d errors, want 2", len(unwrapped))
+	}
+}
+
+func TestGenerateEmptyTemplate(t *testing.T) {
+	tests := []struct {
+		name       string
+		errorEmpty bool
+		wantErr    bool
+	}{
+		{"warn", false, false},
+		{"error", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			templatesDir := filepath.Join(dir, "templates")
+			os.MkdirAll(templatesDir, 0755)
+
+			os.WriteFile(filepath.Join(templatesDir, "home.html.tmpl"), []byte("{{.Title}}"), 0644)
+			os.WriteFile(filepath.Join(templatesDir, "leftover.html.tmpl"), nil, 0644)
+			// Whitespace is output, so only zero-byte files count as empty.
+			os.WriteFile(filepath.Join(templatesDir, "newline.txt.tmpl"), []byte("\n"), 0644)
+
+			cfg := &config.TemplatesConfig{
+				Root:       dir,
+				Package:    "main",
+				Dirs:       []string{"templates/*.tmpl"},
+				ErrorEmpty: tt.errorEmpty,
+			}
+
+			var err error
+			stderr := captureStderr(t, func() { err = NewTemplatesGenerator(cfg).Generate() })
+			if strings.Contains(stderr+fmt.Sprint(err), "newline.txt.tmpl") {
+				t.Errorf("whitespace-only template reported as empty: %v %s", err, stderr)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !strings.Contains(stderr, "warning: empty template templates/leftover.html.tmpl\n") {
+					t.Errorf("expected a warning naming the empty template, got %q", stderr)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected error for empty template")
+			}
+			if !strings.Contains(err.Error(), "templates/leftover.html.tmpl: empty template") {
+				t.Errorf("expected empty template error naming the file, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateWithBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir := filepath.Join(dir, "templates")
+	os.MkdirAll(templatesDir, 0755)
+
+	os.WriteFile(filepath.Join(templatesDir, "tags.html.tmpl"), []byte(`{{join ", " .Tags | upper}}`), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:     dir,
+		Package:  "main",
+		Dirs:     []string{"templates/*.tmpl"},
+		Builtins: true,
+	}
+
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	if !strings.Contains(string(content), "rumtpl.NewManagerFromFSWithBuiltins(") {
+		t.Error("expected manager to be created with builtins")
+	}
+}
+
+func TestGenerateLazy(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir := filepath.Join(dir, "templates")
+	os.MkdirAll(templatesDir, 0755)
+
+	os.WriteFile(filepath.Join(templatesDir, "home.html.tmpl"), []byte("{{.Title}}"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:    dir,
+		Package: "main",
+		Dirs:    []string{"templates/*.tmpl"},
+		Lazy:    true,
+	}
+
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	output := string(content)
+
+	for _, want := range []string{
+		`"sync"`,
+		"managerOnce sync.Once",
+		"func Manager() (*rumtpl.Manager, error)",
+		"func Render(name TemplateName, data any) ([]byte, error)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+
+	if strings.Contains(output, "func init()") || strings.Contains(output, "panic(") {
+		t.Error("expected no init function or panic in lazy mode")
+	}
+}
+
+func TestGenerateExposeFS(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir := filepath.Join(dir, "templates")
+	os.MkdirAll(templatesDir, 0755)
+
+	os.WriteFile(filepath.Join(templatesDir, "home.html.tmpl"), []byte("{{.Title}}"), 0644)
+
+	for _, expose := range []bool{false, true} {
+		cfg := &config.TemplatesConfig{
+			Root:     dir,
+			Package:  "main",
+			Dirs:     []string{"templates/*.tmpl"},
+			ExposeFS: expose,
+		}
+
+		if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+			t.Fatalf("Generate() error: %v", err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+		if err != nil {
+			t.Fatalf("reading output file: %v", err)
+		}
+		output := string(content)
+
+		hasAccessor := strings.Contains(output, "func Templates() fs.FS") && strings.Contains(output, `"io/fs"`)
+		if hasAccessor != expose {
+			t.Errorf("ExposeFS=%v: accessor present = %v", expose, hasAccessor)
+		}
+	}
+}
+
 func TestGenerateDuplicateNames(t *testing.T) {
 	dir := t.TempDir()
 
@@ -198,3 +454,1021 @@ func TestGenerateDuplicateNames(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestGenerateManifest(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates", "pages"), 0755)
+
+	os.WriteFile(filepath.Join(dir, "templates", "base.html.tmpl"), []byte("base"), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "pages", "home.html.tmpl"), []byte("<h1>{{.}}</h1>"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:         dir,
+		Package:      "main",
+		Dirs:         []string{"templates/**/*.tmpl"},
+		EmitManifest: true,
+	}
+
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_manifest.json"))
+	if err != nil {
+		t.Fatalf("reading manifest: %v", err)
+	}
+
+	var manifest struct {
+		Templates []manifestEntry `json:"templates"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		t.Fatalf("parsing manifest: %v", err)
+	}
+
+	want := []manifestEntry{
+		{Path: "templates/base.html.tmpl", ConstName: "Base", Size: 4},
+		{Path: "templates/pages/home.html.tmpl", ConstName: "PagesHome", Size: 14},
+	}
+	if !reflect.DeepEqual(manifest.Templates, want) {
+		t.Errorf("manifest = %+v, want %+v", manifest.Templates, want)
+	}
+}
+
+func TestGenerateWithoutManifest(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte("home"), 0644)
+
+	cfg := &config.TemplatesConfig{Root: dir, Package: "main", Dirs: []string{"templates/*.tmpl"}}
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "templates_manifest.json")); !os.IsNotExist(err) {
+		t.Errorf("expected no manifest, stat error = %v", err)
+	}
+}
+
+func TestGenerateSplitByDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"emails", "pages", "partials"} {
+		os.MkdirAll(filepath.Join(dir, sub), 0755)
+		os.WriteFile(filepath.Join(dir, sub, "a.html.tmpl"), []byte(sub), 0644)
+	}
+
+	userFile := filepath.Join(dir, "templates_custom_gen.go")
+	os.WriteFile(userFile, []byte("package views\n"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:       dir,
+		Package:    "views",
+		Dirs:       []string{"emails/*.tmpl", "pages/*.tmpl", "partials/*.tmpl"},
+		SplitByDir: true,
+	}
+
+	// Splitting twice replaces the group files of the first run.
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	main, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	if strings.Contains(string(main), "const (") {
+		t.Error("templates_gen.go should not declare constants when split")
+	}
+	if !strings.Contains(string(main), "//go:embed") {
+		t.Error("templates_gen.go should keep the embed directives")
+	}
+
+	for _, tc := range []struct{ file, constName string }{
+		{"templates_emails_gen.go", `EmailsA TemplateName = "emails/a.html.tmpl"`},
+		{"templates_pages_gen.go", `PagesA TemplateName = "pages/a.html.tmpl"`},
+		{"templates_partials_gen.go", `PartialsA TemplateName = "partials/a.html.tmpl"`},
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, tc.file))
+		if err != nil {
+			t.Fatalf("reading %s: %v", tc.file, err)
+		}
+		output := string(content)
+		if strings.Count(output, "package views") != 1 {
+			t.Errorf("%s: expected exactly one package clause", tc.file)
+		}
+		if strings.Contains(output, "embed") {
+			t.Errorf("%s: group files must not embed", tc.file)
+		}
+		if !strings.Contains(output, tc.constName) {
+			t.Errorf("%s: missing %s", tc.file, tc.constName)
+		}
+	}
+
+	if _, err := os.Stat(userFile); err != nil {
+		t.Errorf("a file not generated by rum was removed: %v", err)
+	}
+
+	// Turning split_by_dir off removes the generated group files, which
+	// would otherwise declare every constant twice.
+	cfg.SplitByDir = false
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "templates_*_gen.go")); !reflect.DeepEqual(files, []string{userFile}) {
+		t.Errorf("expected only the user file to be left, got %v", files)
+	}
+}
+
+func TestGenerateSplitByDirGroups(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []string
+		wantFiles []string
+		wantErr   string
+	}{
+		{
+			name:      "grouped after strip_prefixes",
+			files:     []string{"templates/emails/a.html.tmpl", "templates/pages/b.html.tmpl"},
+			wantFiles: []string{"templates_emails_gen.go", "templates_pages_gen.go"},
+		},
+		{
+			name:      "root-level templates",
+			files:     []string{"templates/about.tmpl", "templates/home.tmpl", "templates/pages/c.html.tmpl"},
+			wantFiles: []string{"templates_pages_gen.go", "templates_root_gen.go"},
+		},
+		{
+			name:    "colliding group names",
+			files:   []string{"templates/my-pages/a.html.tmpl", "templates/my_pages/b.html.tmpl"},
+			wantErr: `directories "my-pages" and "my_pages" would both be written to templates_my_pages_gen.go`,
+		},
+		{
+			name:    "directory named root",
+			files:   []string{"templates/home.tmpl", "templates/root/b.html.tmpl"},
+			wantErr: `directories "." and "root" would both be written to templates_root_gen.go`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, file := range tt.files {
+				os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755)
+				os.WriteFile(filepath.Join(dir, file), []byte(file), 0644)
+			}
+
+			cfg := &config.TemplatesConfig{
+				Root:       dir,
+				Package:    "views",
+				Dirs:       []string{"templates/**/*.tmpl"},
+				SplitByDir: true,
+			}
+
+			err := NewTemplatesGenerator(cfg).Generate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				if files, _ := filepath.Glob(filepath.Join(dir, "templates*_gen.go")); len(files) != 0 {
+					t.Errorf("a failed run wrote %v", files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error: %v", err)
+			}
+
+			files, _ := filepath.Glob(filepath.Join(dir, "templates_*_gen.go"))
+			for i := range files {
+				files[i] = filepath.Base(files[i])
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("group files = %v, want %v", files, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestGenerateFromManifest(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	for _, name := range []string{"a", "b", "c"} {
+		os.WriteFile(filepath.Join(dir, "templates", name+".html.tmpl"), []byte(name), 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "templates.list"), []byte("# pages in render order\ntemplates/c.html.tmpl\n\ntemplates/a.html.tmpl\n"), 0644)
+
+	cfg := &config.TemplatesConfig{Root: dir, Package: "main", Manifest: "templates.list"}
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	output := string(content)
+
+	c := strings.Index(output, `C TemplateName = "templates/c.html.tmpl"`)
+	a := strings.Index(output, `A TemplateName = "templates/a.html.tmpl"`)
+	if c == -1 || a == -1 || c > a {
+		t.Errorf("expected C then A in manifest order, got:\n%s", output)
+	}
+	if strings.Contains(output, "templates/b.html.tmpl") {
+		t.Error("unlisted template b should not be generated")
+	}
+	if !strings.Contains(output, "//go:embed templates/c.html.tmpl") {
+		t.Error("expected listed files to be embedded individually")
+	}
+
+	// Missing entries and paths outside the root fail generation, naming
+	// the manifest line.
+	outside := filepath.Join(filepath.Dir(dir), "outside.html.tmpl")
+	os.WriteFile(outside, []byte("x"), 0644)
+	for _, tt := range []struct{ entry, want string }{
+		{"templates/missing.html.tmpl", "line 2: listed template templates/missing.html.tmpl"},
+		{"../outside.html.tmpl", "line 2: listed template ../outside.html.tmpl is outside the root"},
+		{"templates/../../outside.html.tmpl", "is outside the root"},
+		{outside, "line 2: listed template " + outside + " must be relative to the root"},
+	} {
+		os.WriteFile(filepath.Join(dir, "templates.list"), []byte("templates/a.html.tmpl\n"+tt.entry+"\n"), 0644)
+		err := NewTemplatesGenerator(cfg).Generate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("entry %q: error = %v, want it to contain %q", tt.entry, err, tt.want)
+		}
+	}
+}
+
+func TestGenerateConstructor(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir := filepath.Join(dir, "templates")
+	os.MkdirAll(templatesDir, 0755)
+
+	os.WriteFile(filepath.Join(templatesDir, "home.html.tmpl"), []byte("{{.Title}}"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:        dir,
+		Package:     "main",
+		Dirs:        []string{"templates/*.tmpl"},
+		Constructor: true,
+	}
+
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	output := string(content)
+
+	if !strings.Contains(output, "func NewManager() (*rumtpl.Manager, error)") {
+		t.Error("expected NewManager constructor in output")
+	}
+	for _, unwanted := range []string{"var Manager", "func init()", "panic("} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("unexpected %q in constructor mode", unwanted)
+		}
+	}
+
+	cfg.Lazy = true
+	if err := NewTemplatesGenerator(cfg).Generate(); err == nil {
+		t.Error("expected error when combining lazy and constructor")
+	}
+}
+
+func TestGenerateTypedRenderFuncs(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates", "emails"), 0755)
+
+	os.WriteFile(filepath.Join(dir, "templates", "emails", "welcome.txt.tmpl"), []byte("Hi {{.Name}}"), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte("home"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:        dir,
+		Package:     "views",
+		Dirs:        []string{"templates/**/*.tmpl"},
+		DataTypes:   map[string]string{"templates/emails/*.tmpl": "models.Welcome"},
+		DataImports: []string{"example.com/app/models"},
+	}
+
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	output := string(content)
+
+	for _, want := range []string{
+		`"example.com/app/models"`,
+		"func RenderEmailsWelcome(r rumtpl.Renderer, data models.Welcome) ([]byte, error)",
+		"return r.Render(EmailsWelcome, data)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	if strings.Contains(output, "func RenderHome(") {
+		t.Error("unexpected typed render func for untyped template")
+	}
+
+	cfg.DataTypes["templates/emails/welcome.*"] = "models.Other"
+	if err := NewTemplatesGenerator(cfg).Generate(); err == nil {
+		t.Error("expected error for a template matched by two data_types patterns")
+	}
+}
+
+func TestGenerateAssets(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.MkdirAll(filepath.Join(dir, "static"), 0755)
+
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte(`<link href="/static/site.css">`), 0644)
+	// Not valid template syntax: assets must never be parsed.
+	os.WriteFile(filepath.Join(dir, "static", "site.css"), []byte("a::before{content:'{{'}"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:    dir,
+		Package: "main",
+		Dirs:    []string{"templates/*.tmpl"},
+		Assets:  []string{"static/*.css"},
+	}
+
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	output := string(content)
+
+	for _, want := range []string{
+		`"io/fs"`,
+		"//go:embed static/*.css\nvar assetsFS embed.FS",
+		"func Assets() fs.FS {\n\treturn assetsFS\n}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	if strings.Contains(output, "site.css\" ") || strings.Contains(output, "Site TemplateName") {
+		t.Error("assets must not become template constants")
+	}
+
+	// The generated package compiles and serves the asset through Assets().
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+func main() {
+	css, err := fs.ReadFile(Assets(), "static/site.css")
+	fmt.Printf("%s %v", css, err)
+}
+`), 0644)
+	if out, want := runGenerated(t, dir), "a::before{content:'{{'} <nil>"; out != want {
+		t.Errorf("generated program printed %s, want %s", out, want)
+	}
+
+	cfg.Assets = []string{"static/*.js"}
+	if err := NewTemplatesGenerator(cfg).Generate(); err == nil {
+		t.Error("expected error for an assets pattern without matches")
+	}
+}
+
+func TestGenerateStripPrefixes(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "views", "pages"), 0755)
+	os.MkdirAll(filepath.Join(dir, "views", "admin", "pages"), 0755)
+
+	os.WriteFile(filepath.Join(dir, "views", "pages", "home.html.tmpl"), []byte("home"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:          dir,
+		Package:       "main",
+		Dirs:          []string{"views/**/*.tmpl"},
+		StripPrefixes: []string{"views/", "admin"},
+	}
+
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	if !strings.Contains(string(content), `PagesHome TemplateName = "views/pages/home.html.tmpl"`) {
+		t.Errorf("expected PagesHome constant, got:\n%s", content)
+	}
+
+	// Stripping can make names collide; duplicates are checked on final names.
+	os.WriteFile(filepath.Join(dir, "views", "admin", "pages", "home.html.tmpl"), []byte("admin"), 0644)
+	err = NewTemplatesGenerator(cfg).Generate()
+	if err == nil || !strings.Contains(err.Error(), `duplicate constant name "PagesHome"`) {
+		t.Errorf("expected duplicate PagesHome error, got %v", err)
+	}
+}
+
+func TestGenerateMissingDir(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowMissing bool
+		wantErr      string
+	}{
+		{"required", false, "configured dir partials does not exist"},
+		{"optional", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+			os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte("home"), 0644)
+
+			cfg := &config.TemplatesConfig{
+				Root:             dir,
+				Package:          "main",
+				Dirs:             []string{"templates/*.tmpl", "partials/**/*.tmpl"},
+				AllowMissingDirs: tt.allowMissing,
+			}
+
+			err := NewTemplatesGenerator(cfg).Generate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error: %v", err)
+			}
+
+			content, _ := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+			if strings.Contains(string(content), "partials") {
+				t.Errorf("missing dir should not be embedded, got:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestGeneratePackagePerDir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "admin", "templates"), 0755)
+	os.MkdirAll(filepath.Join(dir, "Public-Site", "templates"), 0755)
+	os.MkdirAll(filepath.Join(dir, "assets"), 0755)
+
+	os.WriteFile(filepath.Join(dir, "admin", "templates", "users.html.tmpl"), []byte("users"), 0644)
+	os.WriteFile(filepath.Join(dir, "Public-Site", "templates", "home.html.tmpl"), []byte("home"), 0644)
+
+	// Only admin has assets.
+	os.MkdirAll(filepath.Join(dir, "admin", "static"), 0755)
+	os.WriteFile(filepath.Join(dir, "admin", "static", "app.css"), []byte("body{}"), 0644)
+
+	// Directories the go tool ignores, and ones without a valid package
+	// name, do not stop the run.
+	for _, ignored := range []string{"vendor", "testdata", "_old", "2fa", "3d"} {
+		os.MkdirAll(filepath.Join(dir, ignored, "templates"), 0755)
+	}
+	os.WriteFile(filepath.Join(dir, "vendor", "templates", "x.html.tmpl"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "3d", "templates", "scene.html.tmpl"), []byte("scene"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:          dir,
+		Package:       "ignored",
+		Dirs:          []string{"templates/*.tmpl"},
+		Assets:        []string{"static/*.css"},
+		PackagePerDir: true,
+	}
+
+	stderr := captureStderr(t, func() {
+		if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+			t.Fatalf("Generate() error: %v", err)
+		}
+	})
+	if !strings.Contains(stderr, `cannot derive a package name from directory "3d"`) {
+		t.Errorf("expected a warning for the 3d directory, got %q", stderr)
+	}
+	if strings.Contains(stderr, "2fa") {
+		t.Errorf("expected no warning for a directory without templates, got %q", stderr)
+	}
+	for _, ignored := range []string{"vendor", "testdata", "_old", "2fa", "3d"} {
+		if _, err := os.Stat(filepath.Join(dir, ignored, "templates_gen.go")); !os.IsNotExist(err) {
+			t.Errorf("expected no output in %s", ignored)
+		}
+	}
+
+	tests := []struct {
+		subdir  string
+		pkg     string
+		want    string
+		notWant string
+	}{
+		{"admin", "package admin", `Users TemplateName = "templates/users.html.tmpl"`, "home.html.tmpl"},
+		{"admin", "//go:embed static/*.css", "func Assets() fs.FS", "home.html.tmpl"},
+		{"Public-Site", "package publicsite", `Home TemplateName = "templates/home.html.tmpl"`, "users.html.tmpl"},
+		{"Public-Site", "var assetsFS embed.FS", "func Assets() fs.FS", "static/*.css"},
+	}
+	for _, tt := range tests {
+		content, err := os.ReadFile(filepath.Join(dir, tt.subdir, "templates_gen.go"))
+		if err != nil {
+			t.Fatalf("reading %s output: %v", tt.subdir, err)
+		}
+		for _, want := range []string{tt.pkg, tt.want} {
+			if !strings.Contains(string(content), want) {
+				t.Errorf("%s output missing %q:\n%s", tt.subdir, want, content)
+			}
+		}
+		if strings.Contains(string(content), tt.notWant) {
+			t.Errorf("%s output embeds another package's template %q", tt.subdir, tt.notWant)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "assets", "templates_gen.go")); !os.IsNotExist(err) {
+		t.Error("expected no output for a subdirectory without templates")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "templates_gen.go")); !os.IsNotExist(err) {
+		t.Error("expected no output at the root")
+	}
+}
+
+func TestGenerateSkipInvalid(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir := filepath.Join(dir, "templates")
+	os.MkdirAll(templatesDir, 0755)
+
+	os.WriteFile(filepath.Join(templatesDir, "broken.html.tmpl"), []byte("{{.Oops"), 0644)
+	os.WriteFile(filepath.Join(templatesDir, "home.html.tmpl"), []byte("home"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:        dir,
+		Package:     "main",
+		Dirs:        []string{"templates/*.tmpl"},
+		SkipInvalid: true,
+	}
+
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	if !strings.Contains(string(content), `Home TemplateName = "templates/home.html.tmpl"`) {
+		t.Errorf("expected the valid template to be generated, got:\n%s", content)
+	}
+	if strings.Contains(string(content), "broken") {
+		t.Errorf("expected the broken template to be skipped, got:\n%s", content)
+	}
+
+	// The broken template must not reach the runtime manager either.
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "fmt"
+
+func main() {
+	out, err := Manager.Render(Home, nil)
+	fmt.Print(string(out), err)
+}
+`), 0644)
+	if out := runGenerated(t, dir); out != "home<nil>" {
+		t.Errorf("generated program printed %q, want %q", out, "home<nil>")
+	}
+
+	// With nothing valid left, generation still fails.
+	os.Remove(filepath.Join(templatesDir, "home.html.tmpl"))
+	var verr *ValidationError
+	if err := NewTemplatesGenerator(cfg).Generate(); !errors.As(err, &verr) {
+		t.Errorf("expected a ValidationError when no valid template remains, got %v", err)
+	}
+}
+
+func TestGenerateNameStyle(t *testing.T) {
+	tests := []struct {
+		style     string
+		constLine string
+		renamed   bool
+	}{
+		{"", `PagesHome TemplateName = "templates/pages/home.html.tmpl"`, false},
+		{config.NameStylePath, `PagesHome TemplateName = "templates/pages/home.html.tmpl"`, false},
+		{config.NameStyleBase, `Home TemplateName = "home.html.tmpl"`, true},
+		{config.NameStyleFlat, `PagesHome TemplateName = "pages_home.html.tmpl"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			dir := t.TempDir()
+			os.MkdirAll(filepath.Join(dir, "templates", "pages"), 0755)
+			os.WriteFile(filepath.Join(dir, "templates", "pages", "home.html.tmpl"), []byte("home {{.}}"), 0644)
+
+			cfg := &config.TemplatesConfig{
+				Root:      dir,
+				Package:   "main",
+				Dirs:      []string{"templates/**/*.tmpl"},
+				NameStyle: tt.style,
+			}
+			gen := NewTemplatesGenerator(cfg)
+			if err := gen.Generate(); err != nil {
+				t.Fatalf("Generate() error: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+			if err != nil {
+				t.Fatalf("reading output file: %v", err)
+			}
+			if !strings.Contains(string(content), tt.constLine) {
+				t.Errorf("expected %s, got:\n%s", tt.constLine, content)
+			}
+			if got := strings.Contains(string(content), "NewManagerFromFSNamed(templatesFS"); got != tt.renamed {
+				t.Errorf("NewManagerFromFSNamed used = %v, want %v", got, tt.renamed)
+			}
+
+			// The manager built the way the generated code does finds every
+			// template under the name its constant holds.
+			templates, err := gen.discover()
+			if err != nil {
+				t.Fatalf("discover() error: %v", err)
+			}
+			names := map[string]rumtpl.Name{}
+			for _, tmpl := range templates {
+				names[tmpl.RelPath] = rumtpl.Name(tmpl.Name)
+			}
+			m, err := rumtpl.NewManagerFromFSNamed(os.DirFS(dir), "*.tmpl", names, nil)
+			if err != nil {
+				t.Fatalf("NewManagerFromFSNamed() error: %v", err)
+			}
+			for _, tmpl := range templates {
+				out, err := m.Render(rumtpl.Name(tmpl.Name), "page")
+				if err != nil || string(out) != "home page" {
+					t.Errorf("Render(%q) = %q, %v", tmpl.Name, out, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateNameStyleCollision(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates", "a"), 0755)
+	os.MkdirAll(filepath.Join(dir, "templates", "b"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "a", "home.html.tmpl"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "b", "home.html.tmpl"), []byte("b"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:      dir,
+		Package:   "main",
+		Dirs:      []string{"templates/**/*.tmpl"},
+		NameStyle: config.NameStyleBase,
+	}
+	err := NewTemplatesGenerator(cfg).Generate()
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+
+	cfg.NameStyle = "short"
+	err = NewTemplatesGenerator(cfg).Generate()
+	if err == nil || !strings.Contains(err.Error(), `invalid name_style "short"`) {
+		t.Errorf("expected invalid name_style error, got %v", err)
+	}
+}
+
+func TestGenerateInvalidNumericPrefix(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "404.html.tmpl"), []byte("not found"), 0644)
+
+	for _, prefix := range []string{"1", "-", "Tpl-", "T pl"} {
+		cfg := &config.TemplatesConfig{Root: dir, Package: "main", Dirs: []string{"*.tmpl"}, NumericPrefix: prefix}
+		err := NewTemplatesGenerator(cfg).Generate()
+		if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("invalid numeric_prefix %q", prefix)) {
+			t.Errorf("numeric_prefix %q: expected invalid numeric_prefix error, got %v", prefix, err)
+		}
+	}
+
+	cfg := &config.TemplatesConfig{Root: dir, Package: "main", Dirs: []string{"*.tmpl"}, NumericPrefix: "_"}
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Errorf("numeric_prefix \"_\": Generate() error: %v", err)
+	}
+}
+
+func TestGenerateChecksums(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates", "pages"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte("home"), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "pages", "about.html.tmpl"), []byte("about"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:    dir,
+		Package: "main",
+		Dirs:    []string{"templates/**/*.tmpl"},
+	}
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	if !strings.Contains(string(content), "m.SetChecksums(templateChecksums)") {
+		t.Errorf("expected the manager to get the checksums, got:\n%s", content)
+	}
+
+	// Rebuild the generated map and check it against the files on disk, as
+	// the generated manager does against its embedded copies.
+	checksums := map[string]string{}
+	entry := regexp.MustCompile(`"([^"]+)": "([0-9a-f]{64})",`)
+	for _, m := range entry.FindAllStringSubmatch(string(content), -1) {
+		checksums[m[1]] = m[2]
+	}
+	if len(checksums) != 2 {
+		t.Fatalf("expected 2 checksums, got %v", checksums)
+	}
+
+	m, err := rumtpl.NewManagerFromFS(os.DirFS(dir), "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS() error: %v", err)
+	}
+	m.SetChecksums(checksums)
+	if err := m.VerifyIntegrity(); err != nil {
+		t.Errorf("VerifyIntegrity() on fresh output: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte("edited"), 0644)
+	if err := m.VerifyIntegrity(); !errors.Is(err, rumtpl.ErrIntegrity) {
+		t.Errorf("expected ErrIntegrity after editing a template, got %v", err)
+	}
+}
+
+func TestGenerateChecksumsVerifyAtRuntime(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.html.tmpl"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(dir, "b.html"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "fmt"
+
+func main() {
+	fmt.Print(Manager.VerifyIntegrity())
+}
+`), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:    dir,
+		Package: "main",
+		Dirs:    []string{"*.tmpl", "*.html"},
+	}
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	// b.html is not parsed by the manager, so it must not be checksummed.
+	if out := runGenerated(t, dir); out != "<nil>" {
+		t.Errorf("VerifyIntegrity() on fresh output: %s", out)
+	}
+}
+
+func TestGenerateDuplicateDefines(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "layout.html.tmpl"), []byte(`<main>{{block "content" .}}{{end}}</main>`), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte(`{{template "layout.html.tmpl" .}}{{define "content"}}home{{end}}`), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:             dir,
+		Package:          "main",
+		Dirs:             []string{"templates/*.tmpl"},
+		DuplicateDefines: config.DuplicateDefinesError,
+	}
+
+	// A define filling in a block is the intended override, not a conflict.
+	if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "templates", "about.html.tmpl"), []byte(`{{template "layout.html.tmpl" .}}{{- define "content"}}about{{end}}`), 0644)
+	err := NewTemplatesGenerator(cfg).Generate()
+	if err == nil {
+		t.Fatal("expected duplicate define error")
+	}
+	want := `"content" is defined in templates/about.html.tmpl, templates/home.html.tmpl`
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not contain %q", err, want)
+	}
+
+	for _, tt := range []struct {
+		mode     string
+		wantWarn bool
+	}{
+		{"", true},
+		{config.DuplicateDefinesWarn, true},
+		{config.DuplicateDefinesIgnore, false},
+	} {
+		cfg.DuplicateDefines = tt.mode
+		var err error
+		stderr := captureStderr(t, func() { err = NewTemplatesGenerator(cfg).Generate() })
+		if err != nil {
+			t.Errorf("duplicate_defines %q: Generate() error: %v", tt.mode, err)
+		}
+		if got := strings.Contains(stderr, "warning: duplicate template definition: "+want+"\n"); got != tt.wantWarn {
+			t.Errorf("duplicate_defines %q: warning printed = %v, want %v; stderr: %q", tt.mode, got, tt.wantWarn, stderr)
+		}
+	}
+
+	cfg.DuplicateDefines = "fatal"
+	if err := NewTemplatesGenerator(cfg).Generate(); err == nil || !strings.Contains(err.Error(), "invalid duplicate_defines") {
+		t.Errorf("expected invalid duplicate_defines error, got %v", err)
+	}
+}
+
+func TestDefinedNames(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"define", `{{define "a"}}a{{end}}{{define "b"}}b{{end}}`, []string{"a", "b"}},
+		{"raw string name", "{{define `a`}}a{{end}}", []string{"a"}},
+		{"trimmed", `{{- define "a" -}} a {{- end}}`, []string{"a"}},
+		{"block", `<main>{{block "content" .}}{{end}}</main>`, nil},
+		{"nested block", `{{if .}}{{- block "content" . -}}x{{end}}{{end}}`, nil},
+		{"define then template", `{{define "a"}}a{{end}}{{template "a" .}}`, []string{"a"}},
+		{"template then define", `{{template "a" .}}{{define "a"}}a{{end}}`, []string{"a"}},
+		{"commented out", `{{/* {{define "a"}} */}}x`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("file.tmpl").Parse(tt.text)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if got := definedNames(tmpl, tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("definedNames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateDuplicateDefinesPerDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, pkg := range []string{"admin", "site"} {
+		os.MkdirAll(filepath.Join(dir, pkg, "templates"), 0755)
+		os.WriteFile(filepath.Join(dir, pkg, "templates", "a.html.tmpl"), []byte(`{{define "content"}}a{{end}}`), 0644)
+	}
+	// Only site defines "content" twice; admin and site are separate sets.
+	os.WriteFile(filepath.Join(dir, "site", "templates", "b.html.tmpl"), []byte(`{{define "content"}}b{{end}}`), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:             dir,
+		Dirs:             []string{"templates/*.tmpl"},
+		PackagePerDir:    true,
+		DuplicateDefines: config.DuplicateDefinesError,
+	}
+
+	err := NewTemplatesGenerator(cfg).Generate()
+	want := `generating package site: duplicate template definitions: "content" is defined in templates/a.html.tmpl, templates/b.html.tmpl`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected error %q, got %v", want, err)
+	}
+
+	cfg.DuplicateDefines = config.DuplicateDefinesWarn
+	stderr := captureStderr(t, func() { err = NewTemplatesGenerator(cfg).Generate() })
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if strings.Count(stderr, "warning: duplicate template definition") != 1 {
+		t.Errorf("expected one warning, for site only, got %q", stderr)
+	}
+}
+
+func TestGenerateMalformedFrontMatter(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "ok.css.tmpl"), []byte("{{/* rum:cache-control: max-age=60 */}}\nbody {}"), 0644)
+	os.WriteFile(filepath.Join(dir, "templates", "bad.css.tmpl"), []byte("{{/*\nrum:cache-control: max-age=60\ncache forever\n*/}}\nbody {}"), 0644)
+
+	cfg := &config.TemplatesConfig{
+		Root:    dir,
+		Package: "main",
+		Dirs:    []string{"templates/*.tmpl"},
+	}
+	err := NewTemplatesGenerator(cfg).Generate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 1 || verr.Errors[0].Path != "templates/bad.css.tmpl" {
+		t.Errorf("expected a validation error for bad.css.tmpl only, got %v", err)
+	}
+}
+
+// runGenerated builds and runs the main package in dir, which holds
+// generated code, against this checkout of rum and returns its output.
+func runGenerated(t *testing.T, dir string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+
+	repo, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	goMod := "module example.com/generated\n\ngo 1.25\n\nrequire github.com/4Sigma/rum v0.0.0\n\nreplace github.com/4Sigma/rum => " + repo + "\n"
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644)
+	sum, _ := os.ReadFile(filepath.Join(repo, "go.sum"))
+	os.WriteFile(filepath.Join(dir, "go.sum"), sum, 0644)
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, out)
+	}
+	return string(out)
+}
+
+func TestGenerateKeepBOM(t *testing.T) {
+	for _, keepBOM := range []bool{false, true} {
+		dir := t.TempDir()
+		os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+		os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte("\xEF\xBB\xBFhome"), 0644)
+		os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "fmt"
+
+func main() {
+	out, err := Manager.Render(Home, nil)
+	fmt.Printf("%q %v", out, err)
+}
+`), 0644)
+
+		cfg := &config.TemplatesConfig{
+			Root:    dir,
+			Package: "main",
+			Dirs:    []string{"templates/*.tmpl"},
+			KeepBOM: keepBOM,
+		}
+		if err := NewTemplatesGenerator(cfg).Generate(); err != nil {
+			t.Fatalf("Generate() error: %v", err)
+		}
+
+		want := `"home" <nil>`
+		if keepBOM {
+			want = `"\ufeffhome" <nil>`
+		}
+		if out := runGenerated(t, dir); out != want {
+			t.Errorf("keep_bom=%v: generated program printed %s, want %s", keepBOM, out, want)
+		}
+	}
+}
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	fn()
+	w.Close()
+	return <-out
+}
+
+func TestCheckOutputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"admin", "shop"} {
+		os.MkdirAll(filepath.Join(dir, "web", sub, "templates"), 0755)
+		os.WriteFile(filepath.Join(dir, "web", sub, "templates", "home.html.tmpl"), []byte(sub), 0644)
+	}
+
+	perDir := &config.TemplatesConfig{Root: filepath.Join(dir, "web"), Dirs: []string{"templates/*.tmpl"}, PackagePerDir: true}
+	admin := &config.TemplatesConfig{Root: filepath.Join(dir, "web", "admin"), Package: "admin", Dirs: []string{"templates/*.tmpl"}}
+	other := &config.TemplatesConfig{Root: filepath.Join(dir, "other"), Package: "other", Dirs: []string{"templates/*.tmpl"}}
+
+	if err := CheckOutputs([]*config.TemplatesConfig{perDir, other}); err != nil {
+		t.Errorf("CheckOutputs() error for separate outputs: %v", err)
+	}
+
+	err := CheckOutputs([]*config.TemplatesConfig{perDir, admin})
+	want := "more than one templates section writes " + filepath.Join(dir, "web", "admin", "templates_gen.go")
+	if err == nil || err.Error() != want {
+		t.Errorf("CheckOutputs() error = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,122 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/4Sigma/rum/internal/config"
+)
+
+// DefaultWatchInterval is how often Watcher polls template files for changes.
+const DefaultWatchInterval = 500 * time.Millisecond
+
+// Watcher regenerates templates_gen.go whenever a template file is added,
+// removed or modified. It polls the file system, so it needs no platform
+// specific notification support.
+type Watcher struct {
+	gen      *TemplatesGenerator
+	interval time.Duration
+}
+
+// NewWatcher creates a watcher polling every interval (DefaultWatchInterval if zero).
+func NewWatcher(cfg *config.TemplatesConfig, interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	return &Watcher{gen: NewTemplatesGenerator(cfg), interval: interval}
+}
+
+// fileState identifies a version of a watched file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch generates once and then regenerates on every change until ctx is
+// cancelled. Generation errors are reported on stderr and do not stop the
+// loop. A change seen when ctx is cancelled is still regenerated before
+// Watch returns, so no edit is lost on shutdown.
+func (w *Watcher) Watch(ctx context.Context) error {
+	last := w.snapshot()
+	w.regenerate()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if current := w.snapshot(); !maps.Equal(current, last) {
+				w.regenerate()
+			}
+			return nil
+		case <-ticker.C:
+			current := w.snapshot()
+			if maps.Equal(current, last) {
+				continue
+			}
+			last = current
+			w.regenerate()
+		}
+	}
+}
+
+// WatchWithSignals runs Watch until ctx is cancelled or the process receives
+// SIGINT or SIGTERM.
+func (w *Watcher) WatchWithSignals(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return w.Watch(ctx)
+}
+
+func (w *Watcher) regenerate() {
+	if err := w.gen.Generate(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+}
+
+// snapshot records the state of every discovered template and of the
+// manifest file, if configured.
+func (w *Watcher) snapshot() map[string]fileState {
+	root := w.gen.config.Root
+	if root == "" {
+		root = "."
+	}
+
+	paths := []string{}
+	if w.gen.config.Manifest != "" {
+		paths = append(paths, w.gen.config.Manifest)
+	}
+	if w.gen.config.PackagePerDir {
+		subs, _ := w.gen.packageDirs()
+		for _, sub := range subs {
+			rel := relativeTemplatePath(root, sub.config.Root)
+			templates, _ := sub.discover()
+			for _, t := range templates {
+				paths = append(paths, path.Join(rel, t.RelPath))
+			}
+		}
+	} else {
+		templates, _ := w.gen.discover()
+		for _, t := range templates {
+			paths = append(paths, t.RelPath)
+		}
+	}
+
+	state := make(map[string]fileState, len(paths))
+	for _, p := range paths {
+		info, err := os.Stat(filepath.Join(root, p))
+		if err != nil {
+			continue
+		}
+		state[p] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+	return state
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Sigma/rum/internal/config"
+)
+
+func TestWatchWithSignalsStopsOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte("home"), 0644)
+
+	cfg := &config.TemplatesConfig{Root: dir, Package: "main", Dirs: []string{"templates/*.tmpl"}}
+	w := NewWatcher(cfg, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.WatchWithSignals(ctx) }()
+
+	// Wait for the initial generation.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "templates_gen.go")); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("initial generation did not happen")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A change made just before shutdown must still be generated.
+	os.WriteFile(filepath.Join(dir, "templates", "about.html.tmpl"), []byte("about"), 0644)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Watch returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch did not return after cancel")
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+	if !strings.Contains(string(content), "templates/about.html.tmpl") {
+		t.Error("pending change was not regenerated on shutdown")
+	}
+}
+
+func TestWatchRegeneratesOnChange(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.WriteFile(filepath.Join(dir, "templates", "home.html.tmpl"), []byte("home"), 0644)
+
+	cfg := &config.TemplatesConfig{Root: dir, Package: "main", Dirs: []string{"templates/*.tmpl"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewWatcher(cfg, 10*time.Millisecond).Watch(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	os.WriteFile(filepath.Join(dir, "templates", "contact.html.tmpl"), []byte("contact"), 0644)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		content, _ := os.ReadFile(filepath.Join(dir, "templates_gen.go"))
+		if strings.Contains(string(content), "templates/contact.html.tmpl") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("new template was not picked up")
+}
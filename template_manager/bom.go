@@ -0,0 +1,21 @@
+package rumtpl
+
+import "bytes"
+
+// utf8BOM is the byte order mark some Windows editors write at the start of
+// UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// KeepBOM makes the manager parse template files as they are, instead of
+// dropping a leading UTF-8 byte order mark that would otherwise end up in
+// the rendered output.
+func KeepBOM() ManagerOption {
+	return func(o *managerOptions) {
+		o.keepBOM = true
+	}
+}
+
+// StripBOM returns content without its leading UTF-8 byte order mark, if any.
+func StripBOM(content []byte) []byte {
+	return bytes.TrimPrefix(content, utf8BOM)
+}
@@ -0,0 +1,49 @@
+package rumtpl
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestManagerStripsBOM(t *testing.T) {
+	fs := fstest.MapFS{
+		"page.html.tmpl": {Data: []byte("\xEF\xBB\xBFHello {{.}}")},
+		"meta.html.tmpl": {Data: []byte("\xEF\xBB\xBF{{/* rum:title: Home */}}body")},
+	}
+
+	tests := []struct {
+		name string
+		opts []ManagerOption
+		want string
+	}{
+		{"stripped by default", nil, "Hello world"},
+		{"kept", []ManagerOption{KeepBOM()}, "\xEF\xBB\xBFHello world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewManagerFromFS(fs, "*.tmpl", tt.opts...)
+			if err != nil {
+				t.Fatalf("NewManagerFromFS error: %v", err)
+			}
+			out, err := m.Render("page.html.tmpl", "world")
+			if err != nil {
+				t.Fatalf("Render error: %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("Render = %q, want %q", out, tt.want)
+			}
+		})
+	}
+
+	m, err := NewManagerFromFS(fs, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+	if got := m.Meta("meta.html.tmpl")["title"]; got != "Home" {
+		t.Errorf("front matter after a BOM: title = %q, want %q", got, "Home")
+	}
+	if out, _ := m.Render("meta.html.tmpl", nil); string(out) != "body" {
+		t.Errorf("Render = %q, want %q", out, "body")
+	}
+}
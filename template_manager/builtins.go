@@ -0,0 +1,85 @@
+package rumtpl
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Builtins returns the curated set of helper functions registered by
+// NewManagerFromFSWithBuiltins:
+//
+//	upper, lower, title, trim  string case and whitespace helpers
+//	default DEF VALUE          VALUE, or DEF when VALUE is empty
+//	join SEP LIST              elements of LIST joined by SEP
+//	date LAYOUT TIME           TIME formatted with the Go LAYOUT
+//
+// A new map is returned on every call so callers may extend it freely.
+func Builtins() template.FuncMap {
+	return template.FuncMap{
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"title":   title,
+		"trim":    strings.TrimSpace,
+		"default": defaultValue,
+		"join":    join,
+		"date":    date,
+	}
+}
+
+// title upper-cases the first letter of each space-separated word.
+func title(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+		if unicode.IsSpace(prev) {
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, s)
+}
+
+// defaultValue returns value unless it is nil or the zero value of its type.
+func defaultValue(def, value any) any {
+	if value == nil {
+		return def
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+	return value
+}
+
+// join concatenates the elements of a slice or array, separated by sep.
+func join(sep string, list any) (string, error) {
+	if s, ok := list.([]string); ok {
+		return strings.Join(s, sep), nil
+	}
+
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected slice, got %T", list)
+	}
+
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// date formats t using a Go reference-time layout.
+func date(layout string, t time.Time) string {
+	return t.Format(layout)
+}
@@ -0,0 +1,133 @@
+package rumtpl
+
+import (
+	"html/template"
+	"slices"
+	"text/template/parse"
+)
+
+// dependencyGraph maps each template to the templates it includes directly
+// via {{template}} or {{block}}.
+type dependencyGraph map[Name][]Name
+
+// buildDependencyGraph extracts include references from every parsed
+// template. It must run before the first execution, since html/template
+// rewrites the parse trees while escaping.
+func buildDependencyGraph(t *template.Template) dependencyGraph {
+	graph := make(dependencyGraph)
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+
+		refs := make(map[Name]bool)
+		collectTemplateRefs(tmpl.Tree.Root, refs)
+
+		var deps []Name
+		for ref := range refs {
+			deps = append(deps, ref)
+		}
+		slices.Sort(deps)
+		graph[Name(tmpl.Name())] = deps
+	}
+	return graph
+}
+
+// collectTemplateRefs records the names of all templates invoked below node.
+func collectTemplateRefs(node parse.Node, refs map[Name]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectTemplateRefs(child, refs)
+		}
+	case *parse.TemplateNode:
+		refs[Name(n.Name)] = true
+	case *parse.IfNode:
+		collectTemplateRefs(n.List, refs)
+		collectTemplateRefs(n.ElseList, refs)
+	case *parse.RangeNode:
+		collectTemplateRefs(n.List, refs)
+		collectTemplateRefs(n.ElseList, refs)
+	case *parse.WithNode:
+		collectTemplateRefs(n.List, refs)
+		collectTemplateRefs(n.ElseList, refs)
+	}
+}
+
+// Dependencies returns the templates that name includes, directly or
+// transitively, sorted by name.
+func (m *Manager) Dependencies(name Name) []Name {
+	_, _, deps := m.sets()
+	return deps.reachable([]Name{name}, func(n Name) []Name { return deps[n] })
+}
+
+// Dependents returns the templates that include name, directly or
+// transitively, sorted by name. For a template file, templates including one
+// of the blocks it {{define}}s count as well. These are the templates to
+// re-render when name changes, as rum render --watch does.
+func (m *Manager) Dependents(name Name) []Name {
+	_, _, graph := m.sets()
+	m.mu.RLock()
+	starts := append([]Name{name}, m.files[name]...)
+	m.mu.RUnlock()
+
+	reverse := make(dependencyGraph)
+	for parent, deps := range graph {
+		for _, dep := range deps {
+			reverse[dep] = append(reverse[dep], parent)
+		}
+	}
+	return graph.reachable(starts, func(n Name) []Name { return reverse[n] })
+}
+
+// reachable walks edges from starts and returns every visited template
+// except the starts.
+func (g dependencyGraph) reachable(starts []Name, edges func(Name) []Name) []Name {
+	seen := make(map[Name]bool, len(starts))
+	for _, start := range starts {
+		seen[start] = true
+	}
+	queue := slices.Clone(starts)
+
+	var result []Name
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range edges(current) {
+			if seen[next] {
+				continue
+			}
+			seen[next] = true
+			result = append(result, next)
+			queue = append(queue, next)
+		}
+	}
+
+	slices.Sort(result)
+	return result
+}
+
+// definedTemplates returns the names of the {{define}} and {{block}}
+// templates in text, a template file parsed as name. Functions are not
+// checked, so it works without the manager's FuncMap; text that does not
+// parse defines nothing.
+func definedTemplates(name Name, text string) []Name {
+	trees := make(map[string]*parse.Tree)
+	tree := parse.New(string(name))
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(text, "", "", trees); err != nil {
+		return nil
+	}
+
+	var defined []Name
+	for n := range trees {
+		if n != string(name) {
+			defined = append(defined, Name(n))
+		}
+	}
+	slices.Sort(defined)
+	return defined
+}
@@ -0,0 +1,56 @@
+package rumtpl
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDependencies(t *testing.T) {
+	fs := fstest.MapFS{
+		"partials/header.tmpl": {Data: []byte(`<h1>{{.}}</h1>`)},
+		"layout.tmpl":          {Data: []byte(`{{template "partials/header.tmpl" .}}{{block "content" .}}{{end}}`)},
+		"pages/home.tmpl":      {Data: []byte(`{{if .}}{{template "layout.tmpl" .}}{{end}}`)},
+		"pages/about.tmpl":     {Data: []byte(`{{range .}}{{template "partials/header.tmpl" .}}{{end}}`)},
+		"pages/plain.tmpl":     {Data: []byte(`plain`)},
+	}
+
+	m, err := NewManagerFromFS(fs, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	gotDeps := m.Dependencies("pages/home.tmpl")
+	wantDeps := []Name{"content", "layout.tmpl", "partials/header.tmpl"}
+	if !slices.Equal(gotDeps, wantDeps) {
+		t.Errorf("Dependencies() = %v, want %v", gotDeps, wantDeps)
+	}
+
+	gotDependents := m.Dependents("partials/header.tmpl")
+	wantDependents := []Name{"layout.tmpl", "pages/about.tmpl", "pages/home.tmpl"}
+	if !slices.Equal(gotDependents, wantDependents) {
+		t.Errorf("Dependents() = %v, want %v", gotDependents, wantDependents)
+	}
+
+	if deps := m.Dependents("pages/plain.tmpl"); len(deps) != 0 {
+		t.Errorf("expected no dependents, got %v", deps)
+	}
+}
+
+func TestDependentsOfDefinedBlocks(t *testing.T) {
+	m, err := NewManagerFromFS(fstest.MapFS{
+		"partials/nav.tmpl": {Data: []byte(`{{define "nav"}}<nav>{{end}}{{define "footer"}}<footer>{{end}}`)},
+		"layout.tmpl":       {Data: []byte(`{{define "layout"}}{{template "nav"}}{{end}}`)},
+		"pages/home.tmpl":   {Data: []byte(`{{template "layout"}}{{template "footer"}}`)},
+		"pages/plain.tmpl":  {Data: []byte(`plain`)},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	got := m.Dependents("partials/nav.tmpl")
+	want := []Name{"layout", "pages/home.tmpl"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Dependents() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package rumtpl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Template files may start with front matter: a template comment holding
+// only "rum:<key>: <value>" lines, e.g.
+//
+//	{{/*
+//	rum:cache-control: max-age=3600
+//	*/}}
+//
+// The managers strip it before parsing, so it is never rendered, and expose
+// the values through Manager.Meta. A leading comment whose first line does
+// not start with "rum:" is an ordinary comment and is left alone.
+const (
+	frontMatterStart  = "{{/*"
+	frontMatterEnd    = "*/}}"
+	frontMatterPrefix = "rum:"
+)
+
+// ParseFrontMatter splits the front matter off a template file, returning
+// its values keyed by lower-cased key and the remaining template text. The
+// newline after the closing "*/}}" is stripped too. Content without front
+// matter is returned unchanged with nil meta. A front matter line that is
+// not "rum:<key>: <value>" is an error.
+func ParseFrontMatter(content []byte) (meta map[string]string, body []byte, err error) {
+	if !bytes.HasPrefix(content, []byte(frontMatterStart)) {
+		return nil, content, nil
+	}
+	end := bytes.Index(content, []byte(frontMatterEnd))
+	if end < 0 {
+		return nil, content, nil
+	}
+
+	inner := strings.TrimSpace(string(content[len(frontMatterStart):end]))
+	if !strings.HasPrefix(inner, frontMatterPrefix) {
+		return nil, content, nil
+	}
+
+	meta = make(map[string]string)
+	for i, line := range strings.Split(inner, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, frontMatterPrefix), ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !strings.HasPrefix(line, frontMatterPrefix) || !ok || key == "" {
+			return nil, nil, fmt.Errorf("%w: front matter line %d: expected %q, got %q", ErrTemplateError, i+1, "rum:<key>: <value>", line)
+		}
+		meta[key] = strings.TrimSpace(value)
+	}
+
+	body = content[end+len(frontMatterEnd):]
+	if bytes.HasPrefix(body, []byte("\r\n")) {
+		body = body[2:]
+	} else if bytes.HasPrefix(body, []byte("\n")) {
+		body = body[1:]
+	}
+	return meta, body, nil
+}
+
+// Meta returns the front matter values of the named template, e.g.
+// m.Meta(name)["cache-control"] for a handler to apply the template's cache
+// policy. It returns nil for templates without front matter. The map must
+// not be modified.
+func (m *Manager) Meta(name Name) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.meta[name]
+}
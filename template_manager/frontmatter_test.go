@@ -0,0 +1,93 @@
+package rumtpl
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantMeta map[string]string
+		wantBody string
+		wantErr  bool
+	}{
+		{
+			name:     "block",
+			content:  "{{/*\nrum:cache-control: max-age=3600\nrum:Content-Type: text/css\n*/}}\nbody {}",
+			wantMeta: map[string]string{"cache-control": "max-age=3600", "content-type": "text/css"},
+			wantBody: "body {}",
+		},
+		{
+			name:     "single line",
+			content:  "{{/* rum:cache-control: no-store */}}\r\nHello",
+			wantMeta: map[string]string{"cache-control": "no-store"},
+			wantBody: "Hello",
+		},
+		{
+			name:     "ordinary comment",
+			content:  "{{/* just a note */}}\nHello",
+			wantBody: "{{/* just a note */}}\nHello",
+		},
+		{
+			name:     "no front matter",
+			content:  "Hello {{.}}",
+			wantBody: "Hello {{.}}",
+		},
+		{
+			name:    "malformed line",
+			content: "{{/*\nrum:cache-control: max-age=60\ncache forever\n*/}}\nHello",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, body, err := ParseFrontMatter([]byte(tt.content))
+			if tt.wantErr {
+				if !errors.Is(err, ErrTemplateError) {
+					t.Errorf("expected ErrTemplateError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFrontMatter error: %v", err)
+			}
+			if !reflect.DeepEqual(meta, tt.wantMeta) {
+				t.Errorf("meta = %v, want %v", meta, tt.wantMeta)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestManagerMeta(t *testing.T) {
+	fs := fstest.MapFS{
+		"style.css.tmpl": {Data: []byte("{{/*\nrum:cache-control: max-age=3600\n*/}}\nbody { color: {{.}}; }")},
+		"page.html.tmpl": {Data: []byte("page")},
+	}
+	m, err := NewManagerFromFS(fs, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	if got := m.Meta("style.css.tmpl")["cache-control"]; got != "max-age=3600" {
+		t.Errorf("cache-control = %q, want %q", got, "max-age=3600")
+	}
+	if m.Meta("page.html.tmpl") != nil {
+		t.Errorf("expected nil meta without front matter, got %v", m.Meta("page.html.tmpl"))
+	}
+
+	out, err := m.Render("style.css.tmpl", "red")
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	if string(out) != "body { color: red; }" {
+		t.Errorf("front matter was rendered: %q", out)
+	}
+}
@@ -0,0 +1,73 @@
+package rumtpl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrIntegrity = errors.New("template integrity check failed")
+)
+
+// Checksum returns the hex-encoded SHA-256 of a template file's content, as
+// recorded by rum gen and checked by VerifyIntegrity.
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// SetChecksums records the expected checksum of every template file, keyed
+// by its path in the manager's FS. Generated code calls it with the checksums
+// taken at generation time.
+func (m *Manager) SetChecksums(checksums map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checksums = checksums
+}
+
+// VerifyIntegrity re-reads the template files the manager was built from and
+// compares them with the checksums given to SetChecksums, returning
+// ErrIntegrity listing every file that changed, is missing or was never
+// checksummed. This catches embedded files and generated constants drifting
+// apart. Templates added with ParseString are not checked.
+func (m *Manager) VerifyIntegrity() error {
+	m.mu.RLock()
+	fsys, pattern, checksums := m.fsys, m.pattern, m.checksums
+	m.mu.RUnlock()
+
+	if checksums == nil {
+		return fmt.Errorf("%w: no checksums recorded", ErrIntegrity)
+	}
+
+	var problems []string
+	seen := make(map[string]bool, len(checksums))
+	err := walkTemplates(fsys, pattern, func(path string, content []byte) error {
+		seen[path] = true
+		want, ok := checksums[path]
+		switch {
+		case !ok:
+			problems = append(problems, path+": no recorded checksum")
+		case Checksum(content) != want:
+			problems = append(problems, path+": content changed")
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrIntegrity, err)
+	}
+	for path := range checksums {
+		if !seen[path] {
+			problems = append(problems, path+": missing")
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s", ErrIntegrity, strings.Join(problems, "; "))
+	}
+	return nil
+}
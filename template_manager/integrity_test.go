@@ -0,0 +1,49 @@
+package rumtpl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	fs := fstest.MapFS{
+		"home.html.tmpl":  {Data: []byte("home")},
+		"about.html.tmpl": {Data: []byte("about")},
+	}
+	m, err := NewManagerFromFS(fs, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	if err := m.VerifyIntegrity(); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("expected ErrIntegrity without checksums, got %v", err)
+	}
+
+	m.SetChecksums(map[string]string{
+		"home.html.tmpl":  Checksum([]byte("home")),
+		"about.html.tmpl": Checksum([]byte("about")),
+	})
+	if err := m.VerifyIntegrity(); err != nil {
+		t.Errorf("VerifyIntegrity error: %v", err)
+	}
+
+	fs["home.html.tmpl"] = &fstest.MapFile{Data: []byte("changed")}
+	fs["extra.html.tmpl"] = &fstest.MapFile{Data: []byte("extra")}
+	delete(fs, "about.html.tmpl")
+
+	err = m.VerifyIntegrity()
+	if !errors.Is(err, ErrIntegrity) {
+		t.Fatalf("expected ErrIntegrity, got %v", err)
+	}
+	for _, want := range []string{
+		"home.html.tmpl: content changed",
+		"extra.html.tmpl: no recorded checksum",
+		"about.html.tmpl: missing",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
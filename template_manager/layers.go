@@ -0,0 +1,99 @@
+package rumtpl
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"sort"
+)
+
+// NewManagerFromLayers parses templates matching pattern from several file
+// systems, e.g. the embedded templates followed by a theme directory on disk
+// (os.DirFS). Layers are listed from lowest to highest priority and resolved
+// as follows:
+//
+//   - A file present in several layers is taken from the highest one only;
+//     the lower copies are not parsed at all.
+//   - Files are parsed layer by layer, lowest first, so a {{define}} in a
+//     higher layer replaces a {{block}} or {{define}} of the same name from a
+//     lower one. A disk page overriding an embedded page can thus fill in the
+//     blocks of an embedded layout it does not override.
+//
+// Templates are named by their path as with NewManagerFromFS, and a leading
+// UTF-8 byte order mark is dropped from each file. funcs may be nil or
+// Builtins().
+func NewManagerFromLayers(layers []fs.FS, pattern string, funcs template.FuncMap) (*Manager, error) {
+	merged := layeredFS(layers)
+	t := template.New("rum").Funcs(funcs)
+	meta := make(map[Name]map[string]string)
+	files := make(map[Name][]Name)
+	for i, layer := range layers {
+		err := walkTemplates(layer, pattern, func(path string, b []byte) error {
+			if merged.owner(path) != i {
+				return nil
+			}
+			return parseFile(t, meta, files, Name(path), StripBOM(b))
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newManagerFromSet(t, meta, files, merged, pattern)
+}
+
+// layeredFS is the union of its file systems, later ones shadowing earlier
+// ones, as used by NewManagerFromLayers.
+type layeredFS []fs.FS
+
+// Open opens name from the highest layer that has it.
+func (l layeredFS) Open(name string) (fs.File, error) {
+	for i := len(l) - 1; i >= 0; i-- {
+		f, err := l[i].Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir merges the entries of directory name across all layers.
+func (l layeredFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	found := false
+	byName := make(map[string]fs.DirEntry)
+	for _, layer := range l {
+		entries, err := fs.ReadDir(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, e := range entries {
+			byName[e.Name()] = e
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(byName))
+	for _, e := range byName {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// owner returns the index of the highest layer holding name, or -1.
+func (l layeredFS) owner(name string) int {
+	for i := len(l) - 1; i >= 0; i-- {
+		if _, err := fs.Stat(l[i], name); err == nil {
+			return i
+		}
+	}
+	return -1
+}
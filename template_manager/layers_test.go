@@ -0,0 +1,48 @@
+package rumtpl
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewManagerFromLayers(t *testing.T) {
+	embedded := fstest.MapFS{
+		"layout.html.tmpl":      {Data: []byte(`<main>{{block "content" .}}default{{end}}</main><footer>{{block "footer" .}}embedded footer{{end}}</footer>`)},
+		"pages/home.html.tmpl":  {Data: []byte(`{{template "layout.html.tmpl" .}}{{define "content"}}embedded home{{end}}`)},
+		"pages/about.html.tmpl": {Data: []byte(`about`)},
+	}
+
+	disk := t.TempDir()
+	os.MkdirAll(filepath.Join(disk, "pages"), 0755)
+	os.WriteFile(filepath.Join(disk, "pages", "home.html.tmpl"), []byte(`{{template "layout.html.tmpl" .}}{{define "content"}}disk home for {{.}}{{end}}`), 0644)
+	os.WriteFile(filepath.Join(disk, "pages", "contact.html.tmpl"), []byte(`contact`), 0644)
+
+	m, err := NewManagerFromLayers([]fs.FS{embedded, os.DirFS(disk)}, "*.tmpl", nil)
+	if err != nil {
+		t.Fatalf("NewManagerFromLayers error: %v", err)
+	}
+
+	// The disk page replaces the embedded one but keeps the embedded
+	// layout's other blocks.
+	out, err := m.Render("pages/home.html.tmpl", "Ada")
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	if want := "<main>disk home for Ada</main><footer>embedded footer</footer>"; string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	for _, name := range []Name{"pages/about.html.tmpl", "pages/contact.html.tmpl"} {
+		if _, err := m.Render(name, nil); err != nil {
+			t.Errorf("Render(%q) error: %v", name, err)
+		}
+	}
+
+	pages, err := fs.Glob(layeredFS{embedded, os.DirFS(disk)}, "pages/*.tmpl")
+	if err != nil || len(pages) != 3 {
+		t.Errorf("merged pages = %v, %v; want 3 files", pages, err)
+	}
+}
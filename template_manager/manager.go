@@ -1,12 +1,22 @@
 package rumtpl
 
 import (
+	"archive/zip"
 	"bytes"
 	"embed"
 	"errors"
+	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
+	"mime"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -18,17 +28,127 @@ type Renderer interface {
 	Render(Name, any) ([]byte, error)
 }
 
+// StreamRenderer is implemented by renderers that can write output directly
+// to a writer, like Manager.
+type StreamRenderer interface {
+	RenderTo(w io.Writer, name Name, data any) error
+}
+
+// RenderObserver is notified after every render of a Manager, e.g. to feed
+// Prometheus counters and histograms. err is the render error, if any.
+// ObserveRender is called from the rendering goroutine and must be safe for
+// concurrent use.
+type RenderObserver interface {
+	ObserveRender(name Name, dur time.Duration, err error)
+}
+
 // Name type for template identifier.
 type Name string
 
 // Manager holds parsed templates.
-type Manager struct{ t *template.Template }
+type Manager struct {
+	mu     sync.RWMutex
+	src    *template.Template // never executed, so ParseString can still add to it
+	t      *template.Template // clone of src used for rendering
+	strict *template.Template // clone of src with missingkey=error, for RenderStrict
+	deps   dependencyGraph
+
+	observer RenderObserver
+
+	fsys      fs.FS  // source of the parsed files, for VerifyIntegrity
+	pattern   string // file name pattern used when parsing fsys
+	checksums map[string]string
+	meta      map[Name]map[string]string // front matter by template name
+	files     map[Name][]Name            // file-level templates, as opposed to {{define}} blocks, and the blocks each defines
+}
+
+// ManagerOption configures how a Manager parses its template files.
+type ManagerOption func(*managerOptions)
+
+type managerOptions struct {
+	keepBOM bool
+}
 
 // NewManagerFromFS parses templates from any fs.FS matching pattern.
 // Templates are registered with their full relative path as the name.
-func NewManagerFromFS(fsys fs.FS, pattern string) (*Manager, error) {
-	t := template.New("rum")
-	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+func NewManagerFromFS(fsys fs.FS, pattern string, opts ...ManagerOption) (*Manager, error) {
+	return newManager(fsys, pattern, nil, nil, opts)
+}
+
+// NewManagerFromFSWithBuiltins is like NewManagerFromFS but registers the
+// Builtins helper functions before parsing.
+func NewManagerFromFSWithBuiltins(fsys fs.FS, pattern string, opts ...ManagerOption) (*Manager, error) {
+	return newManager(fsys, pattern, Builtins(), nil, opts)
+}
+
+// NewManagerFromFSNamed is like NewManagerFromFS but registers the template
+// at each path listed in names under the mapped name instead, e.g. its base
+// file name. Unlisted templates keep their path. funcs may be nil or
+// Builtins(). Templates referencing each other must use the mapped names.
+func NewManagerFromFSNamed(fsys fs.FS, pattern string, names map[string]Name, funcs template.FuncMap, opts ...ManagerOption) (*Manager, error) {
+	return newManager(fsys, pattern, funcs, names, opts)
+}
+
+// newManager parses templates from fsys with the given functions available,
+// renaming those listed in names.
+func newManager(fsys fs.FS, pattern string, funcs template.FuncMap, names map[string]Name, opts []ManagerOption) (*Manager, error) {
+	var o managerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	t := template.New("rum").Funcs(funcs)
+	meta := make(map[Name]map[string]string)
+	files := make(map[Name][]Name)
+	err := walkTemplates(fsys, pattern, func(path string, b []byte) error {
+		// Use full relative path as template name unless renamed
+		name := Name(path)
+		if n, ok := names[path]; ok {
+			name = n
+		}
+		if !o.keepBOM {
+			b = StripBOM(b)
+		}
+		return parseFile(t, meta, files, name, b)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return newManagerFromSet(t, meta, files, fsys, pattern)
+}
+
+// parseFile strips the front matter of a template file, recording it in
+// meta, and parses the rest into t as name, which it adds to files with the
+// templates it defines.
+func parseFile(t *template.Template, meta map[Name]map[string]string, files map[Name][]Name, name Name, content []byte) error {
+	fm, body, err := ParseFrontMatter(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if fm != nil {
+		meta[name] = fm
+	}
+	if _, err := t.New(string(name)).Parse(string(body)); err != nil {
+		return err
+	}
+	files[name] = definedTemplates(name, string(body))
+	return nil
+}
+
+// newManagerFromSet wraps the templates parsed from fsys in a Manager.
+func newManagerFromSet(t *template.Template, meta map[Name]map[string]string, files map[Name][]Name, fsys fs.FS, pattern string) (*Manager, error) {
+	m := &Manager{src: t, meta: meta, files: files, fsys: fsys, pattern: pattern}
+	if err := m.rebuild(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// walkTemplates calls fn with the path and content of every file in fsys
+// whose base name matches pattern.
+func walkTemplates(fsys fs.FS, pattern string, fn func(path string, content []byte) error) error {
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil || d.IsDir() {
 			return err
 		}
@@ -41,15 +161,91 @@ func NewManagerFromFS(fsys fs.FS, pattern string) (*Manager, error) {
 		if rerr != nil {
 			return rerr
 		}
-		// Use full relative path as template name
-		_, perr := t.New(path).Parse(string(b))
-		return perr
+		return fn(path, b)
 	})
+}
 
+// rebuild makes fresh rendering sets from src. html/template refuses to
+// parse into or clone a set once it has executed, so rendering always
+// happens on clones and src stays untouched. The caller holds m.mu.
+func (m *Manager) rebuild() error {
+	t, err := m.src.Clone()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	strict, err := m.src.Clone()
+	if err != nil {
+		return err
 	}
-	return &Manager{t: t}, nil
+	strict.Option("missingkey=error")
+
+	m.t, m.strict, m.deps = t, strict, buildDependencyGraph(t)
+	return nil
+}
+
+// sets returns the current rendering sets and dependency graph.
+func (m *Manager) sets() (t, strict *template.Template, deps dependencyGraph) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.t, m.strict, m.deps
+}
+
+// SetRenderObserver makes o observe every subsequent Render, RenderStrict,
+// RenderWithType and RenderPooled call. A nil o removes the observer; without
+// one, renders are not timed at all.
+func (m *Manager) SetRenderObserver(o RenderObserver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observer = o
+}
+
+func (m *Manager) renderObserver() RenderObserver {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.observer
+}
+
+// ParseString parses text as a new template called name and adds it to the
+// manager, e.g. for tests or plugins that have no template file. It returns
+// ErrTemplateError if a template with that name exists; use
+// ParseStringOverwrite to replace it. On a parse error the manager is
+// unchanged.
+func (m *Manager) ParseString(name Name, text string) error {
+	return m.parseString(name, text, false)
+}
+
+// ParseStringOverwrite is like ParseString but replaces an existing template
+// of the same name.
+func (m *Manager) ParseStringOverwrite(name Name, text string) error {
+	return m.parseString(name, text, true)
+}
+
+func (m *Manager) parseString(name Name, text string, overwrite bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing := m.src.Lookup(string(name)); existing != nil && existing.Tree != nil && !overwrite {
+		return fmt.Errorf("%w: template %q already exists", ErrTemplateError, name)
+	}
+
+	// Parse into a copy so a failed parse leaves src intact.
+	next, err := m.src.Clone()
+	if err != nil {
+		return err
+	}
+	if _, err := next.New(string(name)).Parse(text); err != nil {
+		return err
+	}
+
+	prev := m.src
+	m.src = next
+	if err := m.rebuild(); err != nil {
+		m.src = prev
+		return err
+	}
+	delete(m.meta, name) // front matter of a replaced file no longer applies
+	m.files[name] = definedTemplates(name, text)
+	return nil
 }
 
 // NewManagerFromEmbed convenience when package embeds templates in subdir.
@@ -61,15 +257,216 @@ func NewManagerFromEmbed(f embed.FS, subdir, pattern string) (*Manager, error) {
 	return NewManagerFromFS(s, pattern)
 }
 
+// NewManagerFromZip parses the templates matching pattern from a zip archive
+// of size bytes read through r, naming them by their path in the archive.
+// r must stay readable for the lifetime of the manager, as VerifyIntegrity
+// reads the files again.
+func NewManagerFromZip(r io.ReaderAt, size int64, pattern string) (*Manager, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading zip archive: %w", ErrTemplateError, err)
+	}
+	return NewManagerFromFS(zr, pattern)
+}
+
+// NewManagerFromZipFile is NewManagerFromZip for the archive at path. The
+// archive is read into memory, so no file handle stays open.
+func NewManagerFromZipFile(path, pattern string) (*Manager, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewManagerFromZip(bytes.NewReader(b), int64(len(b)), pattern)
+}
+
+// names returns the sorted names of the file-level templates held by the
+// manager, leaving out {{define}} blocks.
+func (m *Manager) names() []Name {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]Name, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// hasFile reports whether name is a file-level template of the manager.
+func (m *Manager) hasFile(name Name) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.files[name]
+	return ok
+}
+
 // Render implements Renderer.
 func (m *Manager) Render(name Name, data any) ([]byte, error) {
+	set, _, _ := m.sets()
+	return m.execute(set, name, data)
+}
+
+// RenderTo renders the named template straight into w. Unlike Render it
+// never holds the whole output in memory, so it is the path to use for very
+// large outputs such as reports; wrap w in a bufio.Writer if it is costly
+// to write to in small pieces. On an error w may hold partial output.
+func (m *Manager) RenderTo(w io.Writer, name Name, data any) error {
+	set, _, _ := m.sets()
+	observer := m.renderObserver()
+	if observer == nil {
+		return executeTo(w, set, name, data)
+	}
+
+	start := time.Now()
+	err := executeTo(w, set, name, data)
+	observer.ObserveRender(name, time.Since(start), err)
+	return err
+}
+
+// executeTo renders the template called name from the set t into w.
+func executeTo(w io.Writer, t *template.Template, name Name, data any) error {
+	tmpl := t.Lookup(string(name))
+	if tmpl == nil {
+		return ErrTemplateError
+	}
+	return tmpl.Execute(w, data)
+}
+
+// RenderStrict renders like Render, but referencing a map key that data does
+// not contain is an error naming the key, instead of rendering "<no value>"
+// or an empty string. Use it to catch incomplete template data early.
+func (m *Manager) RenderStrict(name Name, data any) ([]byte, error) {
+	_, strict, _ := m.sets()
+	return m.execute(strict, name, data)
+}
+
+// execute renders the template called name from the set t, reporting it to
+// the observer if one is set.
+func (m *Manager) execute(t *template.Template, name Name, data any) ([]byte, error) {
+	observer := m.renderObserver()
+	if observer == nil {
+		return execute(t, name, data)
+	}
+
+	start := time.Now()
+	out, err := execute(t, name, data)
+	observer.ObserveRender(name, time.Since(start), err)
+	return out, err
+}
+
+// execute renders the template called name from the set t.
+func execute(t *template.Template, name Name, data any) ([]byte, error) {
 	var buf bytes.Buffer
-	t := m.t.Lookup(string(name))
-	if t == nil {
+	tmpl := t.Lookup(string(name))
+	if tmpl == nil {
 		return nil, ErrTemplateError
 	}
-	if err := t.Execute(&buf, data); err != nil {
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
+
+// defaultContentType is used by RenderWithType when the template name has no
+// inner extension with a known MIME type.
+const defaultContentType = "text/plain; charset=utf-8"
+
+// RenderWithType renders like Render and also returns the content type of
+// the output, derived from the extension before the template extension:
+// "text/html; charset=utf-8" for "page.html.tmpl", "application/json" for
+// "api.json.tmpl". Names without a known inner extension get text/plain.
+func (m *Manager) RenderWithType(name Name, data any) ([]byte, string, error) {
+	out, err := m.Render(name, data)
+	if err != nil {
+		return nil, "", err
+	}
+	return out, contentType(string(name)), nil
+}
+
+// contentType maps the inner extension of a template name to a MIME type.
+func contentType(name string) string {
+	base := path.Base(name)
+	inner := path.Ext(strings.TrimSuffix(base, path.Ext(base)))
+	if inner == "" {
+		return defaultContentType
+	}
+	if ct := mime.TypeByExtension(inner); ct != "" {
+		return ct
+	}
+	return defaultContentType
+}
+
+// SanitizeName validates a template name taken from untrusted input, such as
+// a theme or page parameter, and returns its canonical form. Backslashes are
+// treated as slashes and redundant "./" and "//" are cleaned; absolute paths,
+// ".." segments and names of templates the manager does not hold are rejected
+// with ErrTemplateError.
+func (m *Manager) SanitizeName(raw string) (Name, error) {
+	name := strings.ReplaceAll(raw, `\`, "/")
+	if name == "" || strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" || strings.Contains(name, ":") {
+		return "", fmt.Errorf("%w: invalid template name %q", ErrTemplateError, raw)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: invalid template name %q", ErrTemplateError, raw)
+		}
+	}
+
+	name = path.Clean(name)
+	set, _, _ := m.sets()
+	if t := set.Lookup(name); t == nil || t.Tree == nil {
+		return "", fmt.Errorf("%w: unknown template %q", ErrTemplateError, raw)
+	}
+	return Name(name), nil
+}
+
+// maxPooledBufferSize caps the buffers kept by RenderPooled so one very large
+// render does not pin its memory in the pool.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// RenderPooled is like Render but renders into a pooled buffer, avoiding an
+// allocation per call on hot paths. The returned bytes alias the buffer and
+// must not be used after release is called; copy them if they need to outlive
+// it. release is never nil and is safe to call more than once.
+func (m *Manager) RenderPooled(name Name, data any) ([]byte, func(), error) {
+	observer := m.renderObserver()
+	if observer == nil {
+		return m.renderPooled(name, data)
+	}
+
+	start := time.Now()
+	out, release, err := m.renderPooled(name, data)
+	observer.ObserveRender(name, time.Since(start), err)
+	return out, release, err
+}
+
+func (m *Manager) renderPooled(name Name, data any) ([]byte, func(), error) {
+	set, _, _ := m.sets()
+	t := set.Lookup(string(name))
+	if t == nil {
+		return nil, func() {}, ErrTemplateError
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if buf.Cap() <= maxPooledBufferSize {
+				bufferPool.Put(buf)
+			}
+		})
+	}
+
+	if err := t.Execute(buf, data); err != nil {
+		release()
+		return nil, func() {}, err
+	}
+	return buf.Bytes(), release, nil
+}
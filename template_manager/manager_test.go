@@ -1,8 +1,18 @@
 package rumtpl
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
 func TestNewManagerFromFS(t *testing.T) {
@@ -86,3 +96,447 @@ func TestRenderWithPath(t *testing.T) {
 		t.Errorf("got %q, want %q", string(result), expected)
 	}
 }
+
+func TestNewManagerFromFSNamed(t *testing.T) {
+	fs := fstest.MapFS{
+		"templates/pages/home.html.tmpl": {Data: []byte(`{{template "layout.html.tmpl" .}}`)},
+		"templates/layout.html.tmpl":     {Data: []byte("Page: {{.Title}}")},
+	}
+	names := map[string]Name{
+		"templates/pages/home.html.tmpl": "home.html.tmpl",
+		"templates/layout.html.tmpl":     "layout.html.tmpl",
+	}
+
+	m, err := NewManagerFromFSNamed(fs, "*.tmpl", names, nil)
+	if err != nil {
+		t.Fatalf("NewManagerFromFSNamed error: %v", err)
+	}
+
+	result, err := m.Render("home.html.tmpl", map[string]string{"Title": "Home"})
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	if string(result) != "Page: Home" {
+		t.Errorf("got %q, want %q", result, "Page: Home")
+	}
+	if _, err := m.Render("templates/pages/home.html.tmpl", nil); err == nil {
+		t.Error("expected the path name to be replaced")
+	}
+}
+
+func TestNewManagerFromZip(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, content := range map[string]string{
+		"templates/home.html.tmpl": `{{template "templates/title.txt.tmpl" .}}: Hello {{.}}`,
+		"templates/title.txt.tmpl": "Home",
+		"templates/readme.md":      "not a template",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip Create error: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip Close error: %v", err)
+	}
+
+	m, err := NewManagerFromZip(bytes.NewReader(archive.Bytes()), int64(archive.Len()), "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromZip error: %v", err)
+	}
+	result, err := m.Render("templates/home.html.tmpl", "Ada")
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	if string(result) != "Home: Hello Ada" {
+		t.Errorf("got %q, want %q", result, "Home: Hello Ada")
+	}
+	if len(m.names()) != 2 {
+		t.Errorf("names = %v, want the two templates", m.names())
+	}
+
+	path := filepath.Join(t.TempDir(), "templates.zip")
+	os.WriteFile(path, archive.Bytes(), 0644)
+	if _, err := NewManagerFromZipFile(path, "*.tmpl"); err != nil {
+		t.Errorf("NewManagerFromZipFile error: %v", err)
+	}
+
+	if _, err := NewManagerFromZip(strings.NewReader("not a zip"), 9, "*.tmpl"); !errors.Is(err, ErrTemplateError) {
+		t.Errorf("expected ErrTemplateError for invalid archive, got %v", err)
+	}
+}
+
+func TestRenderWithBuiltins(t *testing.T) {
+	fs := fstest.MapFS{
+		"tags.html.tmpl": {Data: []byte(`{{default "Anonymous" .Name}}: {{join ", " .Tags}}`)},
+	}
+
+	m, err := NewManagerFromFSWithBuiltins(fs, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFSWithBuiltins error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data map[string]any
+		want string
+	}{
+		{"with name", map[string]any{"Name": "Ada", "Tags": []string{"go", "rum"}}, "Ada: go, rum"},
+		{"default name", map[string]any{"Name": "", "Tags": []any{1, 2}}, "Anonymous: 1, 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := m.Render("tags.html.tmpl", tt.data)
+			if err != nil {
+				t.Fatalf("Render error: %v", err)
+			}
+			if string(result) != tt.want {
+				t.Errorf("got %q, want %q", string(result), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPooled(t *testing.T) {
+	m, err := NewManagerFromFS(fstest.MapFS{
+		"home.html.tmpl": {Data: []byte("Hello {{.}}")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	for _, name := range []string{"World", "Gopher"} {
+		b, release, err := m.RenderPooled("home.html.tmpl", name)
+		if err != nil {
+			t.Fatalf("RenderPooled error: %v", err)
+		}
+		if string(b) != "Hello "+name {
+			t.Errorf("got %q, want %q", b, "Hello "+name)
+		}
+		release()
+		release()
+	}
+
+	_, release, err := m.RenderPooled("missing.tmpl", nil)
+	if err != ErrTemplateError {
+		t.Errorf("expected ErrTemplateError, got %v", err)
+	}
+	release()
+}
+
+func benchmarkManager(b *testing.B) *Manager {
+	m, err := NewManagerFromFS(fstest.MapFS{
+		"list.html.tmpl": {Data: []byte("<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>")},
+	}, "*.tmpl")
+	if err != nil {
+		b.Fatalf("NewManagerFromFS error: %v", err)
+	}
+	return m
+}
+
+var benchmarkItems = []string{"alpha", "beta", "gamma", "delta", "epsilon", "zeta", "eta", "theta"}
+
+func BenchmarkRender(b *testing.B) {
+	m := benchmarkManager(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := m.Render("list.html.tmpl", benchmarkItems); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderPooled(b *testing.B) {
+	m := benchmarkManager(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		_, release, err := m.RenderPooled("list.html.tmpl", benchmarkItems)
+		if err != nil {
+			b.Fatal(err)
+		}
+		release()
+	}
+}
+
+func BenchmarkRenderTo(b *testing.B) {
+	m := benchmarkManager(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := m.RenderTo(io.Discard, "list.html.tmpl", benchmarkItems); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// countingWriter discards data, counting the bytes written.
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
+
+func TestRenderToLargeOutput(t *testing.T) {
+	row := strings.Repeat("<td>report cell</td>", 50) // 1000 bytes
+	m, err := NewManagerFromFS(fstest.MapFS{
+		"report.html.tmpl": {Data: []byte("{{range .}}<tr>" + row + "</tr>\n{{end}}")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	const rows = 5000 // about 5 MB of output
+	data := make([]struct{}, rows)
+
+	// The first execution escapes the template, which allocates once.
+	if err := m.RenderTo(io.Discard, "report.html.tmpl", data[:1]); err != nil {
+		t.Fatalf("RenderTo error: %v", err)
+	}
+
+	var out countingWriter
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if err := m.RenderTo(&out, "report.html.tmpl", data); err != nil {
+		t.Fatalf("RenderTo error: %v", err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if out < rows*1000 {
+		t.Fatalf("rendered %d bytes, want at least %d", out, rows*1000)
+	}
+	// Render would allocate at least the output size; RenderTo must stay
+	// far below it. TotalAlloc only grows, so GC timing does not matter.
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > uint64(out)/10 {
+		t.Errorf("RenderTo allocated %d bytes for %d bytes of output", alloc, out)
+	}
+}
+
+func TestRenderTo(t *testing.T) {
+	m, err := NewManagerFromFS(fstest.MapFS{"home.html.tmpl": {Data: []byte("Hello {{.}}")}}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.RenderTo(&buf, "home.html.tmpl", "Ada"); err != nil || buf.String() != "Hello Ada" {
+		t.Errorf("RenderTo = %q, %v", buf.String(), err)
+	}
+	if err := m.RenderTo(&buf, "missing.html.tmpl", nil); !errors.Is(err, ErrTemplateError) {
+		t.Errorf("expected ErrTemplateError, got %v", err)
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	m, err := NewManagerFromFS(fstest.MapFS{
+		"themes/dark/home.html.tmpl": {Data: []byte("dark home")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	tests := []struct {
+		raw     string
+		want    Name
+		wantErr bool
+	}{
+		{"themes/dark/home.html.tmpl", "themes/dark/home.html.tmpl", false},
+		{"./themes//dark/home.html.tmpl", "themes/dark/home.html.tmpl", false},
+		{`themes\dark\home.html.tmpl`, "themes/dark/home.html.tmpl", false},
+		{"themes/light/../dark/home.html.tmpl", "", true},
+		{"../../etc/passwd", "", true},
+		{"/themes/dark/home.html.tmpl", "", true},
+		{"C:/themes/dark/home.html.tmpl", "", true},
+		{"themes/dark/missing.html.tmpl", "", true},
+		{"rum", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := m.SanitizeName(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrTemplateError) {
+					t.Errorf("expected ErrTemplateError, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("SanitizeName(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWithType(t *testing.T) {
+	m, err := NewManagerFromFS(fstest.MapFS{
+		"page.html.tmpl": {Data: []byte("<p>{{.}}</p>")},
+		"api.json.tmpl":  {Data: []byte(`{"ok": true}`)},
+		"notes.tmpl":     {Data: []byte("{{.}}")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	tests := []struct {
+		name     Name
+		wantType string
+		wantBody string
+	}{
+		{"page.html.tmpl", "text/html; charset=utf-8", "<p>Ada</p>"},
+		{"api.json.tmpl", "application/json", `{"ok": true}`},
+		{"notes.tmpl", "text/plain; charset=utf-8", "Ada"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.name), func(t *testing.T) {
+			out, ct, err := m.RenderWithType(tt.name, "Ada")
+			if err != nil {
+				t.Fatalf("RenderWithType error: %v", err)
+			}
+			if ct != tt.wantType {
+				t.Errorf("content type = %q, want %q", ct, tt.wantType)
+			}
+			if string(out) != tt.wantBody {
+				t.Errorf("body = %q, want %q", out, tt.wantBody)
+			}
+		})
+	}
+
+	if _, _, err := m.RenderWithType("missing.html.tmpl", nil); !errors.Is(err, ErrTemplateError) {
+		t.Errorf("expected ErrTemplateError, got %v", err)
+	}
+}
+
+func TestRenderStrict(t *testing.T) {
+	m, err := NewManagerFromFS(fstest.MapFS{
+		"welcome.txt.tmpl": {Data: []byte("Hi {{.Name}}, your plan is {{.Plan}}")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	data := map[string]any{"Name": "Ada"}
+
+	// Render keeps the lenient default.
+	if _, err := m.Render("welcome.txt.tmpl", data); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+
+	_, err = m.RenderStrict("welcome.txt.tmpl", data)
+	if err == nil || !strings.Contains(err.Error(), `"Plan"`) {
+		t.Fatalf("expected an error naming the missing key Plan, got %v", err)
+	}
+
+	data["Plan"] = "pro"
+	out, err := m.RenderStrict("welcome.txt.tmpl", data)
+	if err != nil || string(out) != "Hi Ada, your plan is pro" {
+		t.Errorf("RenderStrict = %q, %v", out, err)
+	}
+
+	if _, err := m.RenderStrict("missing.tmpl", data); !errors.Is(err, ErrTemplateError) {
+		t.Errorf("expected ErrTemplateError, got %v", err)
+	}
+}
+
+func TestParseString(t *testing.T) {
+	m, err := NewManagerFromFS(fstest.MapFS{
+		"layout.html.tmpl": {Data: []byte(`{{define "greeting"}}Hello{{end}}<main>{{.}}</main>`)},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	// Render first: templates must still be addable after execution.
+	if _, err := m.Render("layout.html.tmpl", "x"); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+
+	if err := m.ParseString("inline", `{{template "greeting"}}, {{.}}!`); err != nil {
+		t.Fatalf("ParseString error: %v", err)
+	}
+	out, err := m.Render("inline", "Ada")
+	if err != nil || string(out) != "Hello, Ada!" {
+		t.Errorf("Render(inline) = %q, %v", out, err)
+	}
+	if deps := m.Dependencies("inline"); len(deps) != 1 || deps[0] != "greeting" {
+		t.Errorf("Dependencies(inline) = %v", deps)
+	}
+
+	if err := m.ParseString("inline", "other"); !errors.Is(err, ErrTemplateError) {
+		t.Errorf("expected ErrTemplateError for a name collision, got %v", err)
+	}
+	if err := m.ParseString("broken", "{{.Oops"); err == nil {
+		t.Error("expected a parse error")
+	}
+	if _, err := m.Render("broken", nil); !errors.Is(err, ErrTemplateError) {
+		t.Errorf("failed parse should not register a template, got %v", err)
+	}
+
+	if err := m.ParseStringOverwrite("inline", "Bye, {{.}}"); err != nil {
+		t.Fatalf("ParseStringOverwrite error: %v", err)
+	}
+	if out, _ := m.Render("inline", "Ada"); string(out) != "Bye, Ada" {
+		t.Errorf("Render(inline) after overwrite = %q", out)
+	}
+}
+
+type renderObservation struct {
+	name Name
+	dur  time.Duration
+	err  error
+}
+
+type recordingObserver struct {
+	mu           sync.Mutex
+	observations []renderObservation
+}
+
+func (r *recordingObserver) ObserveRender(name Name, dur time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observations = append(r.observations, renderObservation{name, dur, err})
+}
+
+func TestRenderObserver(t *testing.T) {
+	m, err := NewManagerFromFS(fstest.MapFS{
+		"page.html.tmpl": {Data: []byte("<p>{{.}}</p>")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	observer := &recordingObserver{}
+	m.SetRenderObserver(observer)
+
+	m.Render("page.html.tmpl", "hi")
+	_, release, _ := m.RenderPooled("page.html.tmpl", "hi")
+	release()
+	m.Render("missing.html.tmpl", nil)
+
+	want := []struct {
+		name    Name
+		wantErr bool
+	}{
+		{"page.html.tmpl", false},
+		{"page.html.tmpl", false},
+		{"missing.html.tmpl", true},
+	}
+	if len(observer.observations) != len(want) {
+		t.Fatalf("got %d observations, want %d", len(observer.observations), len(want))
+	}
+	for i, w := range want {
+		got := observer.observations[i]
+		if got.name != w.name || got.dur < 0 || (got.err != nil) != w.wantErr {
+			t.Errorf("observation %d = %+v, want name %q, non-negative duration, error %v", i, got, w.name, w.wantErr)
+		}
+	}
+
+	m.SetRenderObserver(nil)
+	m.Render("page.html.tmpl", "hi")
+	if len(observer.observations) != len(want) {
+		t.Error("observer called after being removed")
+	}
+}
@@ -0,0 +1,79 @@
+package rumtpl
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+var (
+	ErrNameCollision = errors.New("template name already registered")
+)
+
+// Registry merges several managers (e.g. one per generated package) behind a
+// single Renderer. Only file-level templates are routed, so managers may each
+// {{define}} partials of the same name such as "content" or "header"; the
+// names of template files must be unique across registered managers.
+// Names are looked up on every Render, so templates added later with
+// ParseString are routed too.
+type Registry struct {
+	mu       sync.RWMutex
+	managers []*Manager
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Add registers m. It fails without registering anything if one of m's
+// template files is already provided by another manager.
+func (r *Registry) Add(m *Manager) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range m.names() {
+		for _, other := range r.managers {
+			if other != m && other.hasFile(name) {
+				return fmt.Errorf("%w: %q", ErrNameCollision, name)
+			}
+		}
+	}
+	if !slices.Contains(r.managers, m) {
+		r.managers = append(r.managers, m)
+	}
+	return nil
+}
+
+// Render implements Renderer by delegating to the manager owning name. If
+// templates added after registration made name ambiguous, it returns
+// ErrNameCollision.
+func (r *Registry) Render(name Name, data any) ([]byte, error) {
+	m, err := r.owner(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.Render(name, data)
+}
+
+// owner returns the registered manager holding the template file name.
+func (r *Registry) owner(name Name) (*Manager, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var owner *Manager
+	for _, m := range r.managers {
+		if !m.hasFile(name) {
+			continue
+		}
+		if owner != nil {
+			return nil, fmt.Errorf("%w: %q", ErrNameCollision, name)
+		}
+		owner = m
+	}
+	if owner == nil {
+		return nil, ErrTemplateError
+	}
+	return owner, nil
+}
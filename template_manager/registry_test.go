@@ -0,0 +1,119 @@
+package rumtpl
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRegistry(t *testing.T) {
+	users, err := NewManagerFromFS(fstest.MapFS{
+		"users/list.html.tmpl": {Data: []byte("Users: {{.}}")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+	billing, err := NewManagerFromFS(fstest.MapFS{
+		"billing/invoice.html.tmpl": {Data: []byte("Invoice {{.}}")},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	r := NewRegistry()
+	if err := r.Add(users); err != nil {
+		t.Fatalf("Add(users) error: %v", err)
+	}
+	if err := r.Add(billing); err != nil {
+		t.Fatalf("Add(billing) error: %v", err)
+	}
+
+	tests := []struct {
+		name Name
+		data any
+		want string
+	}{
+		{"users/list.html.tmpl", "alice", "Users: alice"},
+		{"billing/invoice.html.tmpl", 42, "Invoice 42"},
+	}
+	for _, tt := range tests {
+		got, err := r.Render(tt.name, tt.data)
+		if err != nil {
+			t.Fatalf("Render(%q) error: %v", tt.name, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := r.Render("missing.tmpl", nil); err != ErrTemplateError {
+		t.Errorf("expected ErrTemplateError, got %v", err)
+	}
+}
+
+func TestRegistryCollision(t *testing.T) {
+	fs := fstest.MapFS{
+		"shared/home.html.tmpl": {Data: []byte("Home")},
+	}
+
+	a, err := NewManagerFromFS(fs, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+	b, err := NewManagerFromFS(fs, "*.tmpl")
+	if err != nil {
+		t.Fatalf("NewManagerFromFS error: %v", err)
+	}
+
+	r := NewRegistry()
+	if err := r.Add(a); err != nil {
+		t.Fatalf("Add(a) error: %v", err)
+	}
+	if err := r.Add(b); !errors.Is(err, ErrNameCollision) {
+		t.Errorf("expected ErrNameCollision, got %v", err)
+	}
+}
+
+func TestRegistrySharedPartials(t *testing.T) {
+	newManager := func(page string) *Manager {
+		m, err := NewManagerFromFS(fstest.MapFS{
+			page: {Data: []byte(`{{define "content"}}` + page + `{{end}}[{{template "content"}}]`)},
+		}, "*.tmpl")
+		if err != nil {
+			t.Fatalf("NewManagerFromFS error: %v", err)
+		}
+		return m
+	}
+	users, billing := newManager("users/list.html.tmpl"), newManager("billing/invoice.html.tmpl")
+
+	r := NewRegistry()
+	if err := r.Add(users); err != nil {
+		t.Fatalf("Add(users) error: %v", err)
+	}
+	if err := r.Add(billing); err != nil {
+		t.Fatalf("Add(billing) error: %v", err)
+	}
+
+	got, err := r.Render("billing/invoice.html.tmpl", nil)
+	if err != nil || string(got) != "[billing/invoice.html.tmpl]" {
+		t.Errorf("Render = %q, %v", got, err)
+	}
+	if _, err := r.Render("content", nil); err != ErrTemplateError {
+		t.Errorf("define blocks must not be routed, got %v", err)
+	}
+
+	// Templates parsed after Add are routed as well.
+	if err := users.ParseString("users/new.html.tmpl", "new user"); err != nil {
+		t.Fatalf("ParseString error: %v", err)
+	}
+	if got, err := r.Render("users/new.html.tmpl", nil); err != nil || string(got) != "new user" {
+		t.Errorf("Render after ParseString = %q, %v", got, err)
+	}
+
+	if err := billing.ParseString("users/new.html.tmpl", "clash"); err != nil {
+		t.Fatalf("ParseString error: %v", err)
+	}
+	if _, err := r.Render("users/new.html.tmpl", nil); !errors.Is(err, ErrNameCollision) {
+		t.Errorf("expected ErrNameCollision for an ambiguous name, got %v", err)
+	}
+}